@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/deck"
+	"github.com/mjoliver/glazier-go/internal/bundle"
+	"github.com/mjoliver/glazier-go/internal/config"
+)
+
+const bundleUsage = `usage:
+  glazier bundle build -root build.yaml -o bundle.zip [payload ...]
+  glazier bundle verify bundle.zip`
+
+// bundleMain implements the "glazier bundle" subcommands.
+func bundleMain(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", bundleUsage)
+	}
+	switch args[0] {
+	case "build":
+		return bundleBuild(args[1:])
+	case "verify":
+		if len(args) != 2 {
+			return fmt.Errorf("%s", bundleUsage)
+		}
+		b, err := bundle.Open(args[1])
+		if err != nil {
+			return err
+		}
+		deck.Infof("Bundle OK: root %s, %d files", b.Manifest.Root, len(b.Manifest.Files))
+		return nil
+	default:
+		return fmt.Errorf("unknown bundle command %q\n%s", args[0], bundleUsage)
+	}
+}
+
+func bundleBuild(args []string) error {
+	fs := flag.NewFlagSet("bundle build", flag.ContinueOnError)
+	root := fs.String("root", "build.yaml", "Root config; includes are followed and paths are stored relative to its directory")
+	out := fs.String("o", "bundle.zip", "Output archive (.zip, .tar, .tar.gz or .tgz)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Payloads given on the command line are relative to the root's directory.
+	base := filepath.Dir(*root)
+	var payloads []string
+	for _, p := range fs.Args() {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(base, p)
+		}
+		payloads = append(payloads, p)
+	}
+
+	configs, err := config.LocalFiles(*root)
+	if err != nil {
+		return err
+	}
+	for _, c := range configs {
+		deck.Infof("Adding config: %s", c)
+	}
+	m, err := bundle.Build(*out, base, *root, append(configs[1:], payloads...))
+	if err != nil {
+		return fmt.Errorf("bundle build: %w", err)
+	}
+	deck.Infof("Wrote %s: root %s, %d files", *out, m.Root, len(m.Files))
+
+	// Re-open the result so a broken archive is caught now, not at imaging time.
+	if _, err := bundle.Open(*out); err != nil {
+		os.Remove(*out)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"github.com/google/deck"
+	"github.com/mjoliver/glazier-go/internal/actions"
+	"github.com/mjoliver/glazier-go/internal/download"
+)
+
+// logEvent is the Runner's event sink: it writes task start/finish and
+// progress events to the log, and so to the console and Event Log.
+func logEvent(ev actions.Event) {
+	switch ev.Type {
+	case "start":
+		deck.Infof("Task %d (%s): started", ev.Task, ev.Action)
+	case "done":
+		deck.Infof("Task %d (%s): done", ev.Task, ev.Action)
+	case "failed":
+		deck.Errorf("Task %d (%s): failed: %s", ev.Task, ev.Action, ev.Message)
+	case "progress":
+		deck.Infof("Task %d (%s): %s: %s of %s (%s/s)", ev.Task, ev.Action, ev.Message,
+			download.FormatBytes(ev.Done), download.FormatBytes(ev.Total), download.FormatBytes(int64(ev.Rate)))
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/deck"
 	"github.com/google/deck/backends/eventlog"
+	"github.com/mjoliver/glazier-go/internal/secrets"
 	"golang.org/x/sys/windows/registry"
 )
 
@@ -32,5 +33,5 @@ func init() {
 		fmt.Fprintf(os.Stderr, "FATAL: failed to initialize eventlog: %v\n", err)
 		os.Exit(1)
 	}
-	deck.Add(evt)
+	deck.Add(secrets.RedactBackend(evt))
 }
@@ -5,28 +5,86 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/google/deck"
 	"github.com/google/deck/backends/logger"
 	"github.com/mjoliver/glazier-go/internal/config"
+	"github.com/mjoliver/glazier-go/internal/download"
+	"github.com/mjoliver/glazier-go/internal/facts"
+	"github.com/mjoliver/glazier-go/internal/httpclient"
+	"github.com/mjoliver/glazier-go/internal/ntp"
+	"github.com/mjoliver/glazier-go/internal/policy"
+	"github.com/mjoliver/glazier-go/internal/secrets"
 	"github.com/mjoliver/glazier-go/internal/template"
 )
 
 var (
-	configRootPath = flag.String("config_root_path", "/", "Root path to configuration files")
-	ntpServer      = flag.String("ntp_server", "time.google.com", "NTP server to use for time synchronization")
+	authConfig     = flag.String("auth_config", "", "Path to a YAML file of per-host HTTP credential rules")
+	configRootPath = flag.String("config_root_path", "", "Root config file, directory, base URL or bundle (empty = discover)")
+	cacheDir       = flag.String("download_cache_dir", "", "Directory for the content-addressed download cache (empty = disabled)")
+	cacheMaxSize   = flag.String("download_cache_max_size", "", "Evict least recently used cache entries above this size, e.g. 50GB")
+	cacheLink      = flag.Bool("download_cache_link", false, "Hard-link cache hits into place instead of copying")
+	maxDownloads   = flag.Int("max_concurrent_downloads", 4, "Maximum simultaneous download connections (0 = unlimited)")
+	maxDownRate    = flag.String("max_download_rate", "", "Combined bandwidth cap for downloads, e.g. 10MB (per second)")
+	factsFile      = flag.String("facts_file", "", "Path to a YAML map of facts to use instead of inspecting this machine")
+	keystorePath   = flag.String("keystore", "", "Path to an encrypted keystore for keystore: secret references")
+	keystoreKey    = flag.String("keystore_key", "", "Path to the keystore's key file (base64)")
+	ntpServer      = flag.String("ntp_server", "time.google.com", "Comma-separated NTP servers to sync the clock with at startup (empty = disabled)")
+	ntpThreshold   = flag.Duration("ntp_threshold", 2*time.Second, "Set the clock when it is off by more than this")
 	preserveTasks  = flag.Bool("preserve_tasks", false, "Preserve the local task list on startup")
+	secretDefault  = flag.String("secret_provider", "env", "Provider for secret references without a scheme (env, file, keystore or remote)")
+	secretsURL     = flag.String("secrets_url", "", "Base URL of an HTTP secret endpoint for remote: secret references")
+	strictTmpl     = flag.Bool("strict_templates", false, "Fail on template references to undefined variables instead of rendering \"<no value>\"")
+	trustedKeys    = flag.String("trusted_keys", "", "Path to a file of trusted ed25519 publisher keys (base64, one per line)")
 	verifyUrls     = flag.String("verify_urls", "", "Comma-separated list of URLs to verify reachability")
+	verifyTimeout  = flag.Duration("verify_timeout", policy.DefaultNetworkTimeout, "Time limit for each -verify_urls check")
 	validate       = flag.Bool("validate", false, "Validate the configuration without executing (dry-run)")
+	varsFile       = flag.String("vars_file", "", "Path to a YAML map of template variables")
+	cliVars        = varFlags{}
 )
 
-func main() {
-	flag.Parse()
+func init() {
+	flag.Var(cliVars, "var", "Set a template variable as key=value (repeatable; overrides -vars_file)")
+}
+
+// varFlags collects repeated -var key=value flags.
+type varFlags config.Vars
+
+func (v varFlags) String() string { return fmt.Sprint(config.Vars(v)) }
+
+func (v varFlags) Set(s string) error {
+	key, value, err := config.ParseVar(s)
+	if err != nil {
+		return err
+	}
+	v[key] = value
+	return nil
+}
 
+func main() {
 	// Initialize deck logging with stdout backend
-	deck.Add(logger.Init(os.Stdout, 0))
+	deck.Add(secrets.RedactBackend(logger.Init(os.Stdout, 0)))
 	defer deck.Close()
 
+	if len(os.Args) > 1 && os.Args[1] == "bundle" {
+		if err := bundleMain(os.Args[2:]); err != nil {
+			deck.Errorf("%v", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "secrets" {
+		if err := secretsMain(os.Args[2:]); err != nil {
+			deck.Errorf("%v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	flag.Parse()
+
 	if *validate {
 		deck.Info("Running in VALIDATION mode")
 	} else {
@@ -35,8 +93,6 @@ func main() {
 
 	// Todo: Initialize BuildInfo
 	// Todo: Check WinPE status
-	// Todo: Sync Clock
-	// Todo: Check Battery
 
 	ctx := context.Background()
 	if err := run(ctx); err != nil {
@@ -46,7 +102,104 @@ func main() {
 }
 
 func run(ctx context.Context) error {
-	deck.Infof("Config Root Path: %s", *configRootPath)
+	// A wrong clock makes TLS fail, so sync before anything is fetched.
+	// Failure is not fatal: the clock may well be right.
+	servers := ntp.ParseServers(*ntpServer)
+	ntp.SetServers(servers)
+	if len(servers) > 0 {
+		if *validate {
+			if r, err := ntp.QueryAny(ctx, servers, ntp.DefaultTimeout); err != nil {
+				deck.Warningf("Clock check failed: %v", err)
+			} else {
+				deck.Infof("Clock offset from %s: %s (not adjusted in validation mode)", r.Server, r.Offset)
+			}
+		} else if _, err := ntp.Sync(ctx, servers, *ntpThreshold); err != nil {
+			deck.Warningf("Clock sync failed: %v", err)
+		}
+	}
+
+	// Secrets come first so that -auth_config rules can reference them.
+	if *keystorePath != "" {
+		key, err := secrets.LoadKey(*keystoreKey)
+		if err != nil {
+			return err
+		}
+		ks, err := secrets.OpenKeystore(*keystorePath, key)
+		if err != nil {
+			return err
+		}
+		secrets.Register("keystore", ks)
+		deck.Infof("Loaded keystore %s (%d secrets)", *keystorePath, len(ks.Names()))
+	}
+	if *secretsURL != "" {
+		secrets.Register("remote", &secrets.Remote{URL: *secretsURL})
+	}
+	if err := secrets.SetDefault(*secretDefault); err != nil {
+		return fmt.Errorf("-secret_provider: %w", err)
+	}
+
+	if *authConfig != "" {
+		rules, err := httpclient.LoadAuthConfig(*authConfig)
+		if err != nil {
+			return err
+		}
+		httpclient.SetRules(rules)
+		deck.Infof("Loaded %d HTTP credential rules", len(rules))
+	}
+
+	// Fail before any task runs if a required server cannot be reached.
+	// This follows -auth_config so authenticated endpoints can be checked.
+	if *verifyUrls != "" {
+		var urls []string
+		for _, u := range strings.Split(*verifyUrls, ",") {
+			if u = strings.TrimSpace(u); u != "" {
+				urls = append(urls, u)
+			}
+		}
+		if _, err := policy.VerifyURLs(ctx, urls, *verifyTimeout, nil); err != nil {
+			return fmt.Errorf("-verify_urls: %w", err)
+		}
+	}
+
+	rate, err := download.ParseRate(*maxDownRate)
+	if err != nil {
+		return fmt.Errorf("-max_download_rate: %w", err)
+	}
+	download.SetMaxRate(rate)
+	download.SetMaxConcurrent(*maxDownloads)
+
+	if *cacheDir != "" {
+		maxSize, err := download.ParseSize(*cacheMaxSize)
+		if err != nil {
+			return fmt.Errorf("-download_cache_max_size: %w", err)
+		}
+		download.SetCache(&download.Cache{Dir: *cacheDir, MaxSize: maxSize, Link: *cacheLink})
+		deck.Infof("Download cache: %s", *cacheDir)
+	}
+
+	if *trustedKeys != "" {
+		keys, err := download.LoadKeyRing(*trustedKeys)
+		if err != nil {
+			return err
+		}
+		download.SetTrustedKeys(keys)
+		deck.Infof("Loaded %d trusted publisher keys", len(keys))
+	}
+
+	if *factsFile != "" {
+		p, err := facts.LoadFakeProvider(*factsFile)
+		if err != nil {
+			return err
+		}
+		facts.SetProvider(p)
+		deck.Infof("Using facts from %s", *factsFile)
+	}
+
+	configRoot, err := config.Discover(ctx, *configRootPath)
+	if err != nil {
+		return err
+	}
+	deck.Infof("Config Root Path: %s", httpclient.RedactURL(configRoot))
 
 	// Initialize build info for templates
 	buildInfo, err := template.NewBuildInfo()
@@ -55,18 +208,46 @@ func run(ctx context.Context) error {
 		buildInfo = nil // Continue without template support
 	} else {
 		deck.Infof("Build Info: Hostname=%s, Stage=%s", buildInfo.Hostname, buildInfo.Stage)
+		vars := config.Vars{}
+		if *varsFile != "" {
+			if vars, err = config.LoadVarsFile(*varsFile); err != nil {
+				return err
+			}
+		}
+		for k, v := range cliVars {
+			vars[k] = v
+		}
+		buildInfo.Vars = vars
+
+		f, err := facts.Current(ctx)
+		if err != nil {
+			deck.Warningf("Failed to collect facts: %v", err)
+		} else {
+			deck.Infof("Facts: Model=%s, Serial=%s", f.String("model"), f.String("serial"))
+			buildInfo.Facts = f
+		}
 	}
 
 	// Create Config Runner
 	fetcher := config.NewFetcher(buildInfo)
+	fetcher.SetStrict(*strictTmpl)
 	runner := config.NewRunner(fetcher)
+	runner.SetEventSink(logEvent)
 
 	// Load Config
 	if *validate {
-		tasks, err := runner.LoadConfig(ctx, *configRootPath)
+		tasks, err := runner.LoadConfig(ctx, configRoot)
 		if err != nil {
 			return fmt.Errorf("config load failed: %w", err)
 		}
+		for _, c := range fetcher.VarConflicts() {
+			deck.Warningf("Variable conflict: %s", c)
+		}
+		for _, u := range fetcher.TemplateVariables() {
+			if len(u.Variables) > 0 {
+				deck.Infof("Template variables in %s: %s", httpclient.RedactURL(u.File), strings.Join(u.Variables, ", "))
+			}
+		}
 		if err := config.Validate(ctx, tasks); err != nil {
 			return err
 		}
@@ -75,5 +256,5 @@ func run(ctx context.Context) error {
 	}
 
 	// Execute
-	return runner.Start(ctx, *configRootPath)
+	return runner.Start(ctx, configRoot)
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/deck"
+	"github.com/mjoliver/glazier-go/internal/secrets"
+	"github.com/mjoliver/glazier-go/internal/securefile"
+)
+
+const secretsUsage = `usage:
+  glazier secrets keygen -o keystore.key
+  glazier secrets set -keystore keystore.yaml -key keystore.key NAME  (value read from stdin)
+  glazier secrets list -keystore keystore.yaml -key keystore.key`
+
+// secretsMain implements the "glazier secrets" subcommands, which manage
+// the encrypted keystore.
+func secretsMain(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", secretsUsage)
+	}
+	fs := flag.NewFlagSet("secrets "+args[0], flag.ContinueOnError)
+	switch args[0] {
+	case "keygen":
+		out := fs.String("o", "keystore.key", "Output key file")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		key, err := secrets.GenerateKey()
+		if err != nil {
+			return err
+		}
+		if err := securefile.WriteFile(*out, []byte(key+"\n")); err != nil {
+			return err
+		}
+		deck.Infof("Wrote %s; keep it apart from the keystore and restrict access to it", *out)
+		return nil
+	case "set", "list":
+		path := fs.String("keystore", "keystore.yaml", "Keystore file (created if missing)")
+		keyPath := fs.String("key", "keystore.key", "Key file from 'glazier secrets keygen'")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		key, err := secrets.LoadKey(*keyPath)
+		if err != nil {
+			return err
+		}
+		ks, err := secrets.OpenKeystore(*path, key)
+		if err != nil {
+			return err
+		}
+		if args[0] == "list" {
+			for _, n := range ks.Names() {
+				fmt.Println(n)
+			}
+			return nil
+		}
+		if fs.NArg() != 1 {
+			return fmt.Errorf("%s", secretsUsage)
+		}
+		// Read the value from stdin so it never appears in shell history.
+		value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && value == "" {
+			return fmt.Errorf("reading value from stdin: %w", err)
+		}
+		if err := ks.Set(fs.Arg(0), strings.TrimRight(value, "\r\n")); err != nil {
+			return err
+		}
+		if err := ks.Save(); err != nil {
+			return err
+		}
+		deck.Infof("Stored %s in %s", fs.Arg(0), *path)
+		return nil
+	default:
+		return fmt.Errorf("unknown secrets command %q\n%s", args[0], secretsUsage)
+	}
+}
@@ -0,0 +1,33 @@
+package actions
+
+import "context"
+
+// Event is a notification emitted while the Runner executes tasks.
+type Event struct {
+	Task    int    // 1-based task index, set by the Runner
+	Action  string // action name, e.g. "file.download"
+	Type    string // "start", "progress", "done" or "failed"
+	Message string
+
+	// Progress fields, set for Type "progress".
+	Done  int64   // bytes (or units) completed
+	Total int64   // expected total, -1 if unknown
+	Rate  float64 // units per second
+}
+
+// EventSink receives events. It must not block.
+type EventSink func(Event)
+
+type eventSinkKey struct{}
+
+// WithEventSink returns a context that delivers events emitted by actions to sink.
+func WithEventSink(ctx context.Context, sink EventSink) context.Context {
+	return context.WithValue(ctx, eventSinkKey{}, sink)
+}
+
+// Emit sends ev to the sink attached to ctx, if any.
+func Emit(ctx context.Context, ev Event) {
+	if sink, ok := ctx.Value(eventSinkKey{}).(EventSink); ok && sink != nil {
+		sink(ev)
+	}
+}
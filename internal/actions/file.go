@@ -3,17 +3,20 @@ package actions
 import (
 	"archive/zip"
 	"context"
-	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/google/deck"
+	"github.com/mjoliver/glazier-go/internal/bundle"
+	"github.com/mjoliver/glazier-go/internal/download"
+	"github.com/mjoliver/glazier-go/internal/httpclient"
 	"gopkg.in/yaml.v3"
 )
 
@@ -46,6 +49,10 @@ func (a *FileCopy) Validate() error {
 func (a *FileCopy) Run(ctx context.Context) error {
 	deck.Infof("file.copy: %s -> %s", a.Config.Src, a.Config.Dst)
 
+	if strings.HasPrefix(a.Config.Src, bundle.Scheme) {
+		return copyFromBundle(a.Config.Src, a.Config.Dst)
+	}
+
 	info, err := os.Stat(a.Config.Src)
 	if err != nil {
 		return fmt.Errorf("file.copy: %w", err)
@@ -81,6 +88,31 @@ func copyFile(src, dst string) error {
 	return out.Sync()
 }
 
+// copyFromBundle extracts a payload from a verified config bundle.
+func copyFromBundle(src, dst string) error {
+	in, err := bundle.OpenURI(src)
+	if err != nil {
+		return fmt.Errorf("file.copy: %w", err)
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return fmt.Errorf("file.copy: %w", err)
+	}
+	return out.Sync()
+}
+
 func copyDir(src, dst string) error {
 	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -239,9 +271,17 @@ func (a *FileUnzip) Run(ctx context.Context) error {
 // --- file.download ---
 
 type FileDownloadConfig struct {
-	URL    string `yaml:"url"`
-	Dst    string `yaml:"dst"`
-	SHA256 string `yaml:"sha256"` // Optional checksum
+	URL     string `yaml:"url"`
+	Dst     string `yaml:"dst"`
+	SHA256  string `yaml:"sha256"`   // Optional checksum
+	Chunks  int    `yaml:"chunks"`   // parallel range requests (default 1)
+	MaxRate string `yaml:"max_rate"` // per-task bandwidth cap, e.g. "10MB"
+
+	Checksum             string `yaml:"checksum"`               // "algo:hex", e.g. "sha512:..."
+	ChecksumURL          string `yaml:"checksum_url"`           // manifest such as SHA256SUMS, may be relative to url
+	ChecksumSignatureURL string `yaml:"checksum_signature_url"` // detached ed25519 signature of the manifest
+	InsecureChecksum     bool   `yaml:"insecure_checksum"`      // allow sha1
+	SignatureURL         string `yaml:"signature_url"`          // detached ed25519 signature of the file
 }
 
 type FileDownload struct{ Config FileDownloadConfig }
@@ -259,74 +299,232 @@ func (a *FileDownload) Validate() error {
 	if a.Config.URL == "" || a.Config.Dst == "" {
 		return fmt.Errorf("file.download: url and dst are required")
 	}
+	if a.Config.Chunks < 0 || a.Config.Chunks > 16 {
+		return fmt.Errorf("file.download: chunks must be between 0 and 16 (0 or 1 downloads in a single stream)")
+	}
+	if _, err := download.ParseRate(a.Config.MaxRate); err != nil {
+		return fmt.Errorf("file.download: max_rate: %w", err)
+	}
+
+	set := 0
+	for _, v := range []string{a.Config.SHA256, a.Config.Checksum, a.Config.ChecksumURL} {
+		if v != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return fmt.Errorf("file.download: only one of sha256, checksum and checksum_url may be set")
+	}
+	if a.Config.SHA256 != "" {
+		if _, err := download.ParseChecksum("sha256:"+a.Config.SHA256, false); err != nil {
+			return fmt.Errorf("file.download: sha256: %w", err)
+		}
+	}
+	if a.Config.Checksum != "" {
+		if _, err := download.ParseChecksum(a.Config.Checksum, a.Config.InsecureChecksum); err != nil {
+			return fmt.Errorf("file.download: %w", err)
+		}
+	}
+	if a.Config.ChecksumSignatureURL != "" && a.Config.ChecksumURL == "" {
+		return fmt.Errorf("file.download: checksum_signature_url requires checksum_url")
+	}
 	return nil
 }
 
 func (a *FileDownload) Run(ctx context.Context) error {
-	deck.Infof("file.download: %s -> %s (sha256: %s)", a.Config.URL, a.Config.Dst, a.Config.SHA256)
+	deck.Infof("file.download: %s -> %s", httpclient.RedactURL(a.Config.URL), a.Config.Dst)
 
-	client := &http.Client{Timeout: 5 * time.Minute}
-	req, err := http.NewRequestWithContext(ctx, "GET", a.Config.URL, nil)
+	maxRate, err := download.ParseRate(a.Config.MaxRate)
 	if err != nil {
-		return fmt.Errorf("file.download: %w", err)
+		return fmt.Errorf("file.download: max_rate: %w", err)
 	}
 
-	resp, err := client.Do(req)
+	client := httpclient.New(0)
+	sum, err := a.expectedChecksum(ctx, client)
 	if err != nil {
 		return fmt.Errorf("file.download: %w", err)
 	}
-	defer resp.Body.Close()
+	var sig []byte
+	if a.Config.SignatureURL != "" {
+		if sig, err = fetchSignature(ctx, client, a.Config.URL, a.Config.SignatureURL); err != nil {
+			return fmt.Errorf("file.download: %w", err)
+		}
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("file.download: bad status: %d", resp.StatusCode)
+	req := download.Request{
+		URL:     a.Config.URL,
+		Dst:     a.Config.Dst,
+		Client:  client,
+		Retry:   httpclient.DefaultRetryPolicy,
+		Chunks:  a.Config.Chunks,
+		MaxRate: maxRate,
+		Progress: func(p download.Progress) {
+			Emit(ctx, Event{Type: "progress", Message: a.Config.Dst, Done: p.Done, Total: p.Total, Rate: p.Rate})
+		},
 	}
 
-	// Ensure destination directory
-	if err := os.MkdirAll(filepath.Dir(a.Config.Dst), 0755); err != nil {
-		return err
+	// Skip the transfer if dst or the cache already holds the expected content
+	cache := download.DefaultCache()
+	if sum.Algo == "" && sig != nil && verifySignedFile(a.Config.Dst, sig) == nil {
+		deck.Infof("file.download: %s already present with valid signature, skipping", a.Config.Dst)
+		return nil
+	}
+	if sum.Algo != "" {
+		if download.FileMatches(a.Config.Dst, sum.Algo, sum.Hex) && (sig == nil || verifySignedFile(a.Config.Dst, sig) == nil) {
+			deck.Infof("file.download: %s already present with matching checksum, skipping", a.Config.Dst)
+			return nil
+		}
+		if cache != nil {
+			hit, err := cache.Fetch(sum.Algo, sum.Hex, a.Config.Dst)
+			if err != nil {
+				deck.Warningf("file.download: %v", err)
+			} else if hit {
+				deck.Infof("file.download: %s restored from cache", a.Config.Dst)
+				return nil
+			}
+		}
 	}
 
-	out, err := os.Create(a.Config.Dst)
-	if err != nil {
+	// Hash while streaming so the file is never re-read for verification.
+	// A failed check discards the download.
+	var checks []func() error
+	if sum.Algo != "" {
+		h, err := download.NewHash(sum.Algo)
+		if err != nil {
+			return fmt.Errorf("file.download: %w", err)
+		}
+		req.Hashers = append(req.Hashers, h)
+		checks = append(checks, func() error {
+			actual := hex.EncodeToString(h.Sum(nil))
+			if !strings.EqualFold(actual, sum.Hex) {
+				return fmt.Errorf("expected %s, got %s:%s", sum, sum.Algo, actual)
+			}
+			return nil
+		})
+	}
+	if sig != nil {
+		h := sha512.New()
+		req.Hashers = append(req.Hashers, h)
+		checks = append(checks, func() error {
+			err := download.TrustedKeys().VerifyFile(download.PartialPath(a.Config.Dst), h.Sum(nil), sig)
+			if err != nil {
+				return fmt.Errorf("signature: %w", err)
+			}
+			return nil
+		})
+	}
+	if len(checks) > 0 {
+		req.Verify = func() error {
+			for _, check := range checks {
+				if err := check(); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	if err := download.Get(ctx, req); err != nil {
 		return fmt.Errorf("file.download: %w", err)
 	}
-	defer out.Close()
+	if sig != nil {
+		deck.Infof("file.download: signature verified for %s", a.Config.Dst)
+	}
+	if sum.Algo != "" {
+		deck.Infof("file.download: %s checksum verified for %s", sum.Algo, a.Config.Dst)
+		if cache != nil {
+			if err := cache.Add(sum.Algo, sum.Hex, a.Config.Dst); err != nil {
+				deck.Warningf("file.download: failed to add %s to cache: %v", a.Config.Dst, err)
+			}
+		}
+	}
+	return nil
+}
+
+// expectedChecksum resolves the configured checksum, fetching and verifying
+// the manifest if checksum_url is set. It returns a zero Checksum if none is
+// configured.
+func (a *FileDownload) expectedChecksum(ctx context.Context, client *http.Client) (download.Checksum, error) {
+	switch {
+	case a.Config.SHA256 != "":
+		return download.ParseChecksum("sha256:"+a.Config.SHA256, false)
+	case a.Config.Checksum != "":
+		return download.ParseChecksum(a.Config.Checksum, a.Config.InsecureChecksum)
+	case a.Config.ChecksumURL == "":
+		return download.Checksum{}, nil
+	}
 
-	_, err = io.Copy(out, resp.Body)
+	manifestURL, err := resolveURL(a.Config.URL, a.Config.ChecksumURL)
 	if err != nil {
-		return err
+		return download.Checksum{}, fmt.Errorf("checksum_url: %w", err)
+	}
+	manifest, err := download.FetchSmall(ctx, client, manifestURL)
+	if err != nil {
+		return download.Checksum{}, fmt.Errorf("failed to fetch checksum manifest: %w", err)
 	}
-	out.Close() // Close before verifying
 
-	// Verify Checksum if provided
-	if a.Config.SHA256 != "" {
-		if err := verifyChecksum(a.Config.Dst, a.Config.SHA256); err != nil {
-			os.Remove(a.Config.Dst) // Security: clean up bad file
-			return fmt.Errorf("file.download: checksum verification failed: %w", err)
+	if a.Config.ChecksumSignatureURL != "" {
+		sig, err := fetchSignature(ctx, client, manifestURL, a.Config.ChecksumSignatureURL)
+		if err != nil {
+			return download.Checksum{}, fmt.Errorf("manifest %w", err)
+		}
+		if err := download.TrustedKeys().Verify(manifest, sig); err != nil {
+			return download.Checksum{}, fmt.Errorf("manifest %s: %w", httpclient.RedactURL(manifestURL), err)
 		}
-		deck.Infof("file.download: checksum verified for %s", a.Config.Dst)
+		deck.Infof("file.download: manifest signature verified")
 	}
 
-	return nil
+	u, _ := url.Parse(a.Config.URL)
+	sum, err := download.LookupChecksum(manifest, u.Path, a.Config.InsecureChecksum)
+	if err != nil {
+		return download.Checksum{}, fmt.Errorf("%s: %w", httpclient.RedactURL(manifestURL), err)
+	}
+	return sum, nil
 }
 
-func verifyChecksum(path, expected string) error {
+// fetchSignature downloads and decodes a detached signature.
+func fetchSignature(ctx context.Context, client *http.Client, base, ref string) ([]byte, error) {
+	sigURL, err := resolveURL(base, ref)
+	if err != nil {
+		return nil, fmt.Errorf("signature url: %w", err)
+	}
+	data, err := download.FetchSmall(ctx, client, sigURL)
+	if err != nil {
+		return nil, fmt.Errorf("signature: %w", err)
+	}
+	sig, err := download.ParseSignature(data)
+	if err != nil {
+		return nil, fmt.Errorf("signature %s: %w", httpclient.RedactURL(sigURL), err)
+	}
+	return sig, nil
+}
+
+// verifySignedFile checks an existing file against a detached signature.
+func verifySignedFile(path string, sig []byte) error {
 	f, err := os.Open(path)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-
-	h := sha256.New()
+	h := sha512.New()
 	if _, err := io.Copy(h, f); err != nil {
 		return err
 	}
+	return download.TrustedKeys().VerifyFile(path, h.Sum(nil), sig)
+}
 
-	actual := hex.EncodeToString(h.Sum(nil))
-	if !strings.EqualFold(actual, expected) {
-		return fmt.Errorf("expected %s, got %s", expected, actual)
+// resolveURL resolves ref relative to base, so "SHA256SUMS" refers to the
+// manifest next to the download.
+func resolveURL(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", httpclient.RedactError(err)
 	}
-	return nil
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", httpclient.RedactError(err)
+	}
+	return b.ResolveReference(r).String(), nil
 }
 
 // --- Register all file actions ---
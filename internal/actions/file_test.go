@@ -3,7 +3,11 @@ package actions
 import (
 	"archive/zip"
 	"context"
+	"crypto"
+	"crypto/ed25519"
 	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
 	"encoding/hex"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +15,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/mjoliver/glazier-go/internal/bundle"
+	"github.com/mjoliver/glazier-go/internal/download"
 )
 
 func TestFileCopy_Validate(t *testing.T) {
@@ -59,6 +66,29 @@ func TestFileCopy_Run(t *testing.T) {
 	}
 }
 
+func TestFileCopy_RunBundle(t *testing.T) {
+	tmp := t.TempDir()
+	os.MkdirAll(filepath.Join(tmp, "src", "payloads"), 0755)
+	os.WriteFile(filepath.Join(tmp, "src", "build.yaml"), []byte("tasks: []\n"), 0644)
+	os.WriteFile(filepath.Join(tmp, "src", "payloads", "agent.msi"), []byte("payload"), 0644)
+
+	archive := filepath.Join(tmp, "bundle.zip")
+	_, err := bundle.Build(archive, filepath.Join(tmp, "src"), filepath.Join(tmp, "src", "build.yaml"),
+		[]string{filepath.Join(tmp, "src", "payloads")})
+	if err != nil {
+		t.Fatalf("bundle.Build() error = %v", err)
+	}
+
+	dst := filepath.Join(tmp, "out", "agent.msi")
+	a := &FileCopy{Config: FileCopyConfig{Src: bundle.JoinURI(archive, "payloads/agent.msi"), Dst: dst}}
+	if err := a.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "payload" {
+		t.Errorf("copied content = %q, want %q", data, "payload")
+	}
+}
+
 func TestFileCopy_RunDir(t *testing.T) {
 	tmp := t.TempDir()
 
@@ -244,6 +274,190 @@ func TestFileDownload_Run_Checksum(t *testing.T) {
 	})
 }
 
+func TestFileDownload_Run_SkipsExisting(t *testing.T) {
+	content := []byte("already here")
+	h := sha256.Sum256(content)
+	sum := hex.EncodeToString(h[:])
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	tmp := t.TempDir()
+	dst := filepath.Join(tmp, "existing.txt")
+	os.WriteFile(dst, content, 0644)
+
+	a := &FileDownload{Config: FileDownloadConfig{URL: server.URL, Dst: dst, SHA256: sum}}
+	if err := a.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("server called %d times, want 0 for a matching dst", calls)
+	}
+
+	t.Run("cache hit", func(t *testing.T) {
+		download.SetCache(&download.Cache{Dir: filepath.Join(tmp, "cache")})
+		defer download.SetCache(nil)
+
+		first := filepath.Join(tmp, "first.txt")
+		a := &FileDownload{Config: FileDownloadConfig{URL: server.URL, Dst: first, SHA256: sum}}
+		if err := a.Run(context.Background()); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		second := filepath.Join(tmp, "second.txt")
+		a = &FileDownload{Config: FileDownloadConfig{URL: server.URL, Dst: second, SHA256: sum}}
+		if err := a.Run(context.Background()); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("server called %d times, want 1 with a warm cache", calls)
+		}
+		if data, _ := os.ReadFile(second); string(data) != string(content) {
+			t.Errorf("cached copy = %q", data)
+		}
+	})
+}
+
+func TestFileDownload_Run_ChecksumManifest(t *testing.T) {
+	content := []byte("vendor payload")
+	h := sha512.Sum512(content)
+	manifest := []byte(hex.EncodeToString(h[:]) + "  setup.exe\n" + strings.Repeat("0", 128) + "  other.exe\n")
+
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, manifest))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dl/setup.exe", func(w http.ResponseWriter, r *http.Request) { w.Write(content) })
+	mux.HandleFunc("/dl/SHA512SUMS", func(w http.ResponseWriter, r *http.Request) { w.Write(manifest) })
+	mux.HandleFunc("/dl/SHA512SUMS.sig", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(sig)) })
+	mux.HandleFunc("/sums/SHA512SUMS", func(w http.ResponseWriter, r *http.Request) { w.Write(manifest) })
+	mux.HandleFunc("/sums/SHA512SUMS.sig", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(sig)) })
+	mux.HandleFunc("/dl/bad.sig", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(base64.StdEncoding.EncodeToString(make([]byte, ed25519.SignatureSize))))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	download.SetTrustedKeys(download.KeyRing{pub})
+	defer download.SetTrustedKeys(nil)
+
+	tests := []struct {
+		name        string
+		checksumURL string
+		sigURL      string
+		wantErr     bool
+	}{
+		{"unsigned manifest", "SHA512SUMS", "", false},
+		{"signed manifest", "SHA512SUMS", "SHA512SUMS.sig", false},
+		{"bad signature", "SHA512SUMS", "bad.sig", true},
+		// A relative signature URL is resolved against the manifest it signs.
+		{"manifest elsewhere", "/sums/SHA512SUMS", "SHA512SUMS.sig", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "setup.exe")
+			a := &FileDownload{Config: FileDownloadConfig{
+				URL:                  server.URL + "/dl/setup.exe",
+				Dst:                  dst,
+				ChecksumURL:          tt.checksumURL,
+				ChecksumSignatureURL: tt.sigURL,
+			}}
+			err := a.Run(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			_, statErr := os.Stat(dst)
+			if exists := statErr == nil; exists == tt.wantErr {
+				t.Errorf("dst exists = %v, want %v", exists, !tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileDownload_Run_Signature(t *testing.T) {
+	content := []byte("signed payload")
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	digest := sha512.Sum512(content)
+	phSig, _ := priv.Sign(nil, digest[:], &ed25519.Options{Hash: crypto.SHA512})
+	pureSig := ed25519.Sign(priv, content)
+
+	served := content
+	calls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/setup.exe", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write(served)
+	})
+	mux.HandleFunc("/setup.exe.sig", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(base64.StdEncoding.EncodeToString(phSig)))
+	})
+	mux.HandleFunc("/setup.exe.pure.sig", func(w http.ResponseWriter, r *http.Request) { w.Write(pureSig) })
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	download.SetTrustedKeys(download.KeyRing{pub})
+	defer download.SetTrustedKeys(nil)
+
+	tmp := t.TempDir()
+	dst := filepath.Join(tmp, "setup.exe")
+	a := &FileDownload{Config: FileDownloadConfig{URL: server.URL + "/setup.exe", Dst: dst, SignatureURL: "setup.exe.sig"}}
+
+	t.Run("valid prehashed signature", func(t *testing.T) {
+		if err := a.Run(context.Background()); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if data, _ := os.ReadFile(dst); string(data) != string(content) {
+			t.Errorf("dst = %q", data)
+		}
+	})
+
+	t.Run("existing signed file skipped", func(t *testing.T) {
+		before := calls
+		if err := a.Run(context.Background()); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		if calls != before {
+			t.Errorf("server called %d times, want 0 for a verified dst", calls-before)
+		}
+	})
+
+	t.Run("valid plain signature", func(t *testing.T) {
+		dst := filepath.Join(tmp, "pure.exe")
+		a := &FileDownload{Config: FileDownloadConfig{URL: server.URL + "/setup.exe", Dst: dst, SignatureURL: "setup.exe.pure.sig"}}
+		if err := a.Run(context.Background()); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	})
+
+	t.Run("tampered file deleted", func(t *testing.T) {
+		served = []byte("tampered payload")
+		defer func() { served = content }()
+		dst := filepath.Join(tmp, "tampered.exe")
+		a := &FileDownload{Config: FileDownloadConfig{URL: server.URL + "/setup.exe", Dst: dst, SignatureURL: "setup.exe.sig"}}
+		if err := a.Run(context.Background()); err == nil {
+			t.Fatal("Run() expected error for tampered file")
+		}
+		for _, p := range []string{dst, download.PartialPath(dst)} {
+			if _, err := os.Stat(p); !os.IsNotExist(err) {
+				t.Errorf("%s should have been deleted", p)
+			}
+		}
+	})
+
+	t.Run("untrusted key", func(t *testing.T) {
+		other, _, _ := ed25519.GenerateKey(nil)
+		download.SetTrustedKeys(download.KeyRing{other})
+		defer download.SetTrustedKeys(download.KeyRing{pub})
+		a := &FileDownload{Config: FileDownloadConfig{URL: server.URL + "/setup.exe", Dst: filepath.Join(tmp, "other.exe"), SignatureURL: "setup.exe.sig"}}
+		if err := a.Run(context.Background()); err == nil {
+			t.Fatal("Run() expected error for untrusted signature")
+		}
+	})
+}
+
 func TestFileDownload_Validate(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -253,6 +467,15 @@ func TestFileDownload_Validate(t *testing.T) {
 		{"valid", FileDownloadConfig{URL: "http://x.com/f", Dst: "/tmp/f"}, false},
 		{"missing url", FileDownloadConfig{Dst: "/tmp/f"}, true},
 		{"missing dst", FileDownloadConfig{URL: "http://x.com/f"}, true},
+		{"chunks and rate", FileDownloadConfig{URL: "http://x.com/f", Dst: "/tmp/f", Chunks: 4, MaxRate: "10MB"}, false},
+		{"too many chunks", FileDownloadConfig{URL: "http://x.com/f", Dst: "/tmp/f", Chunks: 64}, true},
+		{"negative chunks", FileDownloadConfig{URL: "http://x.com/f", Dst: "/tmp/f", Chunks: -1}, true},
+		{"bad rate", FileDownloadConfig{URL: "http://x.com/f", Dst: "/tmp/f", MaxRate: "fast"}, true},
+		{"sha512 checksum", FileDownloadConfig{URL: "http://x.com/f", Dst: "/tmp/f", Checksum: "sha512:" + strings.Repeat("ab", 64)}, false},
+		{"sha1 without opt-in", FileDownloadConfig{URL: "http://x.com/f", Dst: "/tmp/f", Checksum: "sha1:" + strings.Repeat("ab", 20)}, true},
+		{"sha1 with opt-in", FileDownloadConfig{URL: "http://x.com/f", Dst: "/tmp/f", Checksum: "sha1:" + strings.Repeat("ab", 20), InsecureChecksum: true}, false},
+		{"sha256 and checksum_url", FileDownloadConfig{URL: "http://x.com/f", Dst: "/tmp/f", SHA256: strings.Repeat("ab", 32), ChecksumURL: "SHA256SUMS"}, true},
+		{"signature without manifest", FileDownloadConfig{URL: "http://x.com/f", Dst: "/tmp/f", ChecksumSignatureURL: "SHA256SUMS.sig"}, true},
 	}
 
 	for _, tt := range tests {
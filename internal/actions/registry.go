@@ -14,6 +14,10 @@ type RegistryConfig struct {
 	Name  string      `yaml:"name"`  // value name
 	Value interface{} `yaml:"value"` // for set: string, int, or []string
 	Type  string      `yaml:"type"`  // "string", "dword", "multi_string", "binary"
+
+	// Register names a runtime variable that receives the value read by
+	// registry.get, for use in later ${{ .Runtime.name }} templates.
+	Register string `yaml:"register"`
 }
 
 // --- registry.set ---
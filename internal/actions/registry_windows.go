@@ -67,5 +67,6 @@ func (a *RegistryGet) Run(ctx context.Context) error {
 
 	a.Result = val
 	deck.Infof("registry.get: %s = %q", a.Config.Name, val)
+	SetRuntimeVar(ctx, a.Config.Register, val)
 	return nil
 }
@@ -0,0 +1,51 @@
+package actions
+
+import (
+	"context"
+	"sync"
+)
+
+// RuntimeVars holds values produced while tasks run, such as the current
+// stage or a value read by registry.get. Late-bound ${{ }} templates are
+// rendered against them.
+type RuntimeVars struct {
+	mu   sync.Mutex
+	vars map[string]interface{}
+}
+
+// NewRuntimeVars returns an empty store.
+func NewRuntimeVars() *RuntimeVars {
+	return &RuntimeVars{vars: map[string]interface{}{}}
+}
+
+// Set stores a value.
+func (v *RuntimeVars) Set(name string, value interface{}) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.vars[name] = value
+}
+
+// Snapshot returns a copy of the current values.
+func (v *RuntimeVars) Snapshot() map[string]interface{} {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	out := make(map[string]interface{}, len(v.vars))
+	for k, val := range v.vars {
+		out[k] = val
+	}
+	return out
+}
+
+type runtimeVarsKey struct{}
+
+// WithRuntimeVars returns a context through which actions can publish values.
+func WithRuntimeVars(ctx context.Context, v *RuntimeVars) context.Context {
+	return context.WithValue(ctx, runtimeVarsKey{}, v)
+}
+
+// SetRuntimeVar stores a value in the store attached to ctx, if any.
+func SetRuntimeVar(ctx context.Context, name string, value interface{}) {
+	if v, ok := ctx.Value(runtimeVarsKey{}).(*RuntimeVars); ok && v != nil && name != "" {
+		v.Set(name, value)
+	}
+}
@@ -35,7 +35,11 @@ func NewStageSet(ctx context.Context, yamlData interface{}) (Action, error) {
 func (a *StageSet) Run(ctx context.Context) error {
 	deck.Infof("Setting stage to: %s", a.ID)
 	// Call existing library
-	return stages.SetStage(a.ID, stages.StartKey)
+	if err := stages.SetStage(a.ID, stages.StartKey); err != nil {
+		return err
+	}
+	SetRuntimeVar(ctx, "stage", a.ID)
+	return nil
 }
 
 func (a *StageSet) Validate() error {
@@ -0,0 +1,185 @@
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Build writes a bundle to out. root and files are local paths under base;
+// directories in files are added recursively. The archive format follows
+// the extension of out: .zip, .tar, .tar.gz or .tgz.
+func Build(out, base, root string, files []string) (*Manifest, error) {
+	m := &Manifest{Version: 1, Files: map[string]string{}}
+	paths := map[string]string{} // member name -> local path
+
+	add := func(p string) error {
+		name, err := relName(base, p)
+		if err != nil {
+			return err
+		}
+		if _, ok := paths[name]; ok {
+			return nil
+		}
+		sum, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+		paths[name] = p
+		m.Files[name] = "sha256:" + sum
+		return nil
+	}
+
+	rootName, err := relName(base, root)
+	if err != nil {
+		return nil, err
+	}
+	m.Root = rootName
+	if err := add(root); err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		err := filepath.Walk(f, func(p string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			return add(p)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeArchive(out, manifest, names, paths); err != nil {
+		os.Remove(out)
+		return nil, err
+	}
+	return m, nil
+}
+
+// relName returns p as a slash-separated member name relative to base.
+func relName(base, p string) (string, error) {
+	rel, err := filepath.Rel(base, p)
+	if err != nil {
+		return "", err
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == ".." || strings.HasPrefix(rel, "../") || filepath.IsAbs(rel) {
+		return "", fmt.Errorf("%s is outside the bundle directory %s", p, base)
+	}
+	if rel == ManifestName {
+		return "", fmt.Errorf("%s is reserved for the bundle manifest", ManifestName)
+	}
+	return rel, nil
+}
+
+func hashFile(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeArchive writes the manifest followed by every file.
+func writeArchive(out string, manifest []byte, names []string, paths map[string]string) error {
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if isZip(out) {
+		zw := zip.NewWriter(f)
+		w, err := zw.Create(ManifestName)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(manifest); err != nil {
+			return err
+		}
+		for _, name := range names {
+			w, err := zw.Create(name)
+			if err != nil {
+				return err
+			}
+			if err := copyFrom(w, paths[name]); err != nil {
+				return err
+			}
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		return f.Close()
+	}
+
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if isGzip(out) {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	if err := tw.WriteHeader(&tar.Header{Name: ManifestName, Mode: 0644, Size: int64(len(manifest))}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return err
+	}
+	for _, name := range names {
+		info, err := os.Stat(paths[name])
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime()}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if err := copyFrom(tw, paths[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+	return f.Close()
+}
+
+func copyFrom(w io.Writer, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,387 @@
+// Package bundle reads and writes config bundles: a zip or tar archive
+// holding a root config, its includes and payloads, plus a manifest of
+// every file's hash. Bundles are addressed as bundle://<archive>#<member>.
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/google/deck"
+	"github.com/mjoliver/glazier-go/internal/download"
+)
+
+// Scheme prefixes bundle URIs.
+const Scheme = "bundle://"
+
+// ManifestName is the archive member holding the Manifest.
+const ManifestName = "glazier-bundle.json"
+
+// Manifest lists the bundle's root config and the checksum of every file.
+type Manifest struct {
+	Version int               `json:"version"`
+	Root    string            `json:"root"`  // root config, e.g. "build.yaml"
+	Files   map[string]string `json:"files"` // name -> "sha256:<hex>"
+}
+
+// Bundle is an opened, verified archive.
+type Bundle struct {
+	Path     string
+	Manifest Manifest
+}
+
+var (
+	mu     sync.Mutex
+	opened = map[string]*Bundle{}
+)
+
+// ParseURI splits bundle://<archive>#<member>. member is empty if the URI
+// names only the archive.
+func ParseURI(uri string) (archive, member string, err error) {
+	if !strings.HasPrefix(uri, Scheme) {
+		return "", "", fmt.Errorf("%q is not a bundle URI", uri)
+	}
+	rest := strings.TrimPrefix(uri, Scheme)
+	archive, member, _ = strings.Cut(rest, "#")
+	if archive == "" {
+		return "", "", fmt.Errorf("bundle URI %q has no archive path", uri)
+	}
+	return archive, member, nil
+}
+
+// JoinURI builds a bundle URI.
+func JoinURI(archive, member string) string {
+	return Scheme + archive + "#" + member
+}
+
+// ReadURI returns the contents of the member named by uri, or of the
+// bundle's root config if the URI has no member.
+func ReadURI(uri string) ([]byte, error) {
+	rc, err := OpenURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// OpenURI opens the member named by uri for reading. The contents are
+// re-checked against the manifest as they are read.
+func OpenURI(uri string) (io.ReadCloser, error) {
+	archive, member, err := ParseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	b, err := Open(archive)
+	if err != nil {
+		return nil, err
+	}
+	if member == "" {
+		member = b.Manifest.Root
+	}
+	return b.Open(member)
+}
+
+// Open opens and verifies the bundle at path. Every file is hashed and
+// compared with the manifest before Open returns; verified bundles are
+// cached for the life of the process.
+func Open(archive string) (*Bundle, error) {
+	abs, err := filepath.Abs(archive)
+	if err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if b, ok := opened[abs]; ok {
+		return b, nil
+	}
+
+	b := &Bundle{Path: abs}
+	if err := b.verify(); err != nil {
+		return nil, fmt.Errorf("bundle %s: %w", archive, err)
+	}
+	deck.Infof("bundle: verified %d files in %s", len(b.Manifest.Files), archive)
+	opened[abs] = b
+	return b, nil
+}
+
+// verify reads the manifest and checks every archive member against it.
+func (b *Bundle) verify() error {
+	data, err := b.readMember(ManifestName)
+	if err != nil {
+		return fmt.Errorf("reading manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, &b.Manifest); err != nil {
+		return fmt.Errorf("parsing manifest: %w", err)
+	}
+	if b.Manifest.Version != 1 {
+		return fmt.Errorf("unsupported manifest version %d", b.Manifest.Version)
+	}
+	if _, ok := b.Manifest.Files[b.Manifest.Root]; !ok {
+		return fmt.Errorf("root config %q is not in the manifest", b.Manifest.Root)
+	}
+
+	seen := map[string]bool{}
+	err = b.walk(func(name string, r io.Reader) error {
+		if name == ManifestName {
+			return nil
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate member %s", name)
+		}
+		seen[name] = true
+		want, ok := b.Manifest.Files[name]
+		if !ok {
+			return fmt.Errorf("%s is not listed in the manifest", name)
+		}
+		h, sum, err := newHash(want)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if _, err := io.Copy(h, r); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if got := hex.EncodeToString(h.Sum(nil)); got != sum.Hex {
+			return fmt.Errorf("%s: checksum mismatch: expected %s, got %s:%s", name, sum, sum.Algo, got)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for name := range b.Manifest.Files {
+		if !seen[name] {
+			return fmt.Errorf("%s is listed in the manifest but missing", name)
+		}
+	}
+	return nil
+}
+
+// Open opens a member for reading. Reading to EOF fails if the contents no
+// longer match the manifest.
+func (b *Bundle) Open(name string) (io.ReadCloser, error) {
+	name = path.Clean(strings.TrimPrefix(name, "./"))
+	want, ok := b.Manifest.Files[name]
+	if !ok {
+		return nil, fmt.Errorf("bundle %s has no file %s", b.Path, name)
+	}
+	h, sum, err := newHash(want)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := b.openMember(name)
+	if err != nil {
+		return nil, err
+	}
+	return &verifyingReader{rc: rc, h: h, sum: sum, name: name}, nil
+}
+
+// ReadFile returns the contents of a member.
+func (b *Bundle) ReadFile(name string) ([]byte, error) {
+	rc, err := b.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func newHash(checksum string) (hash.Hash, download.Checksum, error) {
+	sum, err := download.ParseChecksum(checksum, false)
+	if err != nil {
+		return nil, sum, err
+	}
+	h, err := download.NewHash(sum.Algo)
+	return h, sum, err
+}
+
+type verifyingReader struct {
+	rc   io.ReadCloser
+	h    hash.Hash
+	sum  download.Checksum
+	name string
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.rc.Read(p)
+	v.h.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(v.h.Sum(nil)) != v.sum.Hex {
+		return n, fmt.Errorf("%s changed since the bundle was verified", v.name)
+	}
+	return n, err
+}
+
+func (v *verifyingReader) Close() error { return v.rc.Close() }
+
+// --- archive access ---
+
+func isZip(name string) bool { return strings.EqualFold(filepath.Ext(name), ".zip") }
+
+func isGzip(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+}
+
+// memberName validates an archive member name and returns it in clean,
+// slash-separated form. Directories return "".
+func memberName(name string, dir bool) (string, error) {
+	if dir || strings.HasSuffix(name, "/") {
+		return "", nil
+	}
+	clean := path.Clean(strings.TrimPrefix(name, "./"))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || strings.Contains(clean, `\`) {
+		return "", fmt.Errorf("illegal member name %q", name)
+	}
+	return clean, nil
+}
+
+// walk calls fn for every regular file in the archive.
+func (b *Bundle) walk(fn func(name string, r io.Reader) error) error {
+	if isZip(b.Path) {
+		zr, err := zip.OpenReader(b.Path)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			name, err := memberName(f.Name, f.FileInfo().IsDir())
+			if err != nil {
+				return err
+			}
+			if name == "" {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = fn(name, rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tr, closer, err := openTar(b.Path)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			if hdr.Typeflag == tar.TypeDir {
+				continue
+			}
+			return fmt.Errorf("unsupported member type for %s", hdr.Name)
+		}
+		name, err := memberName(hdr.Name, false)
+		if err != nil {
+			return err
+		}
+		if err := fn(name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// readMember reads a whole member without checking the manifest.
+func (b *Bundle) readMember(name string) ([]byte, error) {
+	rc, err := b.openMember(name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// openMember opens a member. Tar archives are scanned from the start.
+func (b *Bundle) openMember(name string) (io.ReadCloser, error) {
+	if isZip(b.Path) {
+		zr, err := zip.OpenReader(b.Path)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if n, _ := memberName(f.Name, f.FileInfo().IsDir()); n == name {
+				rc, err := f.Open()
+				if err != nil {
+					zr.Close()
+					return nil, err
+				}
+				return &multiCloser{Reader: rc, closers: []io.Closer{rc, zr}}, nil
+			}
+		}
+		zr.Close()
+		return nil, fmt.Errorf("%s not found in archive", name)
+	}
+
+	tr, closer, err := openTar(b.Path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			closer.Close()
+			if err == io.EOF {
+				return nil, fmt.Errorf("%s not found in archive", name)
+			}
+			return nil, err
+		}
+		if n, _ := memberName(hdr.Name, hdr.Typeflag == tar.TypeDir); n == name && hdr.Typeflag == tar.TypeReg {
+			return &multiCloser{Reader: tr, closers: []io.Closer{closer}}, nil
+		}
+	}
+}
+
+// openTar opens a plain or gzip-compressed tar archive.
+func openTar(p string) (*tar.Reader, io.Closer, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isGzip(p) {
+		return tar.NewReader(f), f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return tar.NewReader(gz), &multiCloser{closers: []io.Closer{gz, f}}, nil
+}
+
+type multiCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m *multiCloser) Close() error {
+	var first error
+	for _, c := range m.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
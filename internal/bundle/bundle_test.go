@@ -0,0 +1,150 @@
+package bundle
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBuildAndOpen(t *testing.T) {
+	src := t.TempDir()
+	writeFiles(t, src, map[string]string{
+		"build.yaml":         "include:\n  - common/base.yaml\n",
+		"common/base.yaml":   "tasks: []\n",
+		"payloads/agent.msi": "binary",
+	})
+
+	for _, ext := range []string{".zip", ".tar", ".tar.gz"} {
+		t.Run(ext, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "bundle"+ext)
+			files := []string{filepath.Join(src, "common/base.yaml"), filepath.Join(src, "payloads")}
+			m, err := Build(out, src, filepath.Join(src, "build.yaml"), files)
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if m.Root != "build.yaml" || len(m.Files) != 3 {
+				t.Errorf("manifest = %+v", m)
+			}
+
+			data, err := ReadURI(Scheme + out)
+			if err != nil || !strings.HasPrefix(string(data), "include:") {
+				t.Errorf("ReadURI(root) = %q, %v", data, err)
+			}
+			data, err = ReadURI(JoinURI(out, "payloads/agent.msi"))
+			if err != nil || string(data) != "binary" {
+				t.Errorf("ReadURI(payload) = %q, %v", data, err)
+			}
+			if _, err := ReadURI(JoinURI(out, "missing.yaml")); err == nil {
+				t.Error("ReadURI() of a missing member should fail")
+			}
+		})
+	}
+}
+
+func TestBuild_OutsideBase(t *testing.T) {
+	src := t.TempDir()
+	other := t.TempDir()
+	writeFiles(t, src, map[string]string{"build.yaml": "tasks: []\n"})
+	writeFiles(t, other, map[string]string{"x.bin": "x"})
+
+	_, err := Build(filepath.Join(t.TempDir(), "b.zip"), src, filepath.Join(src, "build.yaml"), []string{filepath.Join(other, "x.bin")})
+	if err == nil {
+		t.Error("Build() accepted a file outside the bundle directory")
+	}
+}
+
+// writeZip creates a zip with the given members, bypassing Build.
+func writeZip(t *testing.T, path string, members map[string]string) {
+	t.Helper()
+	f, _ := os.Create(path)
+	zw := zip.NewWriter(f)
+	for name, content := range members {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	f.Close()
+}
+
+func TestOpen_Verification(t *testing.T) {
+	otherSum := "sha256:" + strings.Repeat("0", 64)
+	src := t.TempDir()
+	writeFiles(t, src, map[string]string{"build.yaml": "tasks: []\n"})
+	good := filepath.Join(t.TempDir(), "good.zip")
+	m, err := Build(good, src, filepath.Join(src, "build.yaml"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	realSum := m.Files["build.yaml"]
+
+	manifest := func(files string) string {
+		return `{"version": 1, "root": "build.yaml", "files": {` + files + `}}`
+	}
+	tests := []struct {
+		name    string
+		members map[string]string
+		wantErr string
+	}{
+		{"tampered file", map[string]string{
+			ManifestName: manifest(`"build.yaml": "` + realSum + `"`),
+			"build.yaml": "tasks: [evil]\n",
+		}, "checksum mismatch"},
+		{"unlisted file", map[string]string{
+			ManifestName: manifest(`"build.yaml": "` + realSum + `"`),
+			"build.yaml": "tasks: []\n",
+			"extra.ps1":  "evil",
+		}, "not listed"},
+		{"missing file", map[string]string{
+			ManifestName: manifest(`"build.yaml": "` + realSum + `", "other.yaml": "` + otherSum + `"`),
+			"build.yaml": "tasks: []\n",
+		}, "missing"},
+		{"no manifest", map[string]string{"build.yaml": "tasks: []\n"}, "manifest"},
+		{"path traversal", map[string]string{
+			ManifestName:   manifest(`"build.yaml": "` + realSum + `"`),
+			"build.yaml":   "tasks: []\n",
+			"../evil.yaml": "x",
+		}, "illegal"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := filepath.Join(t.TempDir(), "b.zip")
+			writeZip(t, p, tt.members)
+			_, err := Open(p)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Open() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseURI(t *testing.T) {
+	tests := []struct {
+		uri, archive, member string
+		wantErr              bool
+	}{
+		{"bundle://D:/build.zip#build.yaml", "D:/build.zip", "build.yaml", false},
+		{"bundle:///media/usb/b.tgz#sub/x.yaml", "/media/usb/b.tgz", "sub/x.yaml", false},
+		{"bundle://b.zip", "b.zip", "", false},
+		{"bundle://#x.yaml", "", "", true},
+		{"http://example.com/b.zip", "", "", true},
+	}
+	for _, tt := range tests {
+		archive, member, err := ParseURI(tt.uri)
+		if (err != nil) != tt.wantErr || archive != tt.archive || member != tt.member {
+			t.Errorf("ParseURI(%q) = %q, %q, %v", tt.uri, archive, member, err)
+		}
+	}
+}
@@ -2,23 +2,34 @@ package config
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/deck"
 	"gopkg.in/yaml.v3"
 
 	"github.com/mjoliver/glazier-go/internal/actions"
+	"github.com/mjoliver/glazier-go/internal/bundle"
+	"github.com/mjoliver/glazier-go/internal/httpclient"
 	"github.com/mjoliver/glazier-go/internal/policy"
+	"github.com/mjoliver/glazier-go/internal/secrets"
+	"github.com/mjoliver/glazier-go/internal/template"
 )
 
 // Config represents the schema of a configuration file.
 type Config struct {
-	Includes []string                 `yaml:"include"`
-	Tasks    []map[string]interface{} `yaml:"tasks"`
+	Includes  []string                 `yaml:"include"`
+	Vars      Vars                     `yaml:"vars"`
+	Templates []string                 `yaml:"templates"`
+	Tasks     []map[string]interface{} `yaml:"tasks"`
 	// Backwards compatibility: if the root is just a list, we handle that during unmarshal?
 	// Actually, YAML v3 might struggle if we unmarshal a list into a struct.
 	// We might need a custom UnmarshalYAML or try to unmarshal into []map first, then struct.
@@ -35,7 +46,7 @@ func parseConfigData(data []byte) (*Config, error) {
 	// Try Unmarshalling as the new Struct format
 	var c Config
 	err := yaml.Unmarshal(data, &c)
-	if err == nil && (len(c.Tasks) > 0 || len(c.Includes) > 0) {
+	if err == nil && (len(c.Tasks) > 0 || len(c.Includes) > 0 || len(c.Vars) > 0 || len(c.Templates) > 0) {
 		return &c, nil
 	}
 
@@ -57,6 +68,7 @@ type TaskList []map[string]interface{}
 type Runner struct {
 	tasks   TaskList
 	fetcher FetcherInterface
+	events  actions.EventSink
 }
 
 // NewRunner creates a new Runner.
@@ -66,6 +78,19 @@ func NewRunner(f FetcherInterface) *Runner {
 	}
 }
 
+// SetEventSink registers a receiver for task start/finish and progress events.
+func (r *Runner) SetEventSink(sink actions.EventSink) {
+	r.events = sink
+}
+
+// emit delivers ev to the registered sink, if any.
+func (r *Runner) emit(ev actions.Event) {
+	if r.events != nil {
+		ev.Message = secrets.Redact(ev.Message)
+		r.events(ev)
+	}
+}
+
 // Start executes the task list processing, starting from the given config path.
 func (r *Runner) Start(ctx context.Context, configURL string) error {
 	tasks, err := r.LoadConfig(ctx, configURL)
@@ -73,6 +98,8 @@ func (r *Runner) Start(ctx context.Context, configURL string) error {
 		return err
 	}
 	r.tasks = tasks
+	runtime := actions.NewRuntimeVars()
+	ctx = actions.WithRuntimeVars(ctx, runtime)
 
 	for i, task := range r.tasks {
 		deck.Infof("Executing task %d/%d", i+1, len(r.tasks))
@@ -95,6 +122,13 @@ func (r *Runner) Start(ctx context.Context, configURL string) error {
 				return fmt.Errorf("unknown action: %s", key)
 			}
 
+			// Render ${{ }} against values set by earlier tasks, and resolve
+			// secret: references only now, so values are held as briefly as possible
+			val, err := renderTask(ctx, val, runtime.Snapshot())
+			if err != nil {
+				return fmt.Errorf("action %s: %w", key, err)
+			}
+
 			action, err := factory(ctx, val)
 			if err != nil {
 				return fmt.Errorf("failed to create action %s: %w", key, err)
@@ -104,18 +138,63 @@ func (r *Runner) Start(ctx context.Context, configURL string) error {
 				return fmt.Errorf("action %s validation failed: %w", key, err)
 			}
 
-			if err := runWithRetry(ctx, key, action, retries); err != nil {
+			task, name := i+1, key
+			actx := actions.WithEventSink(ctx, func(ev actions.Event) {
+				ev.Task, ev.Action = task, name
+				r.emit(ev)
+			})
+			actions.Emit(actx, actions.Event{Type: "start"})
+
+			if err := runWithRetry(actx, key, action, retries); err != nil {
+				actions.Emit(actx, actions.Event{Type: "failed", Message: err.Error()})
 				if onError == "continue" {
 					deck.Warningf("Action %s failed (continuing): %v", key, err)
 					continue
 				}
 				return fmt.Errorf("action %s execution failed: %w", key, err)
 			}
+			actions.Emit(actx, actions.Event{Type: "done"})
 		}
 	}
 	return nil
 }
 
+// renderTask renders the late-bound expressions and resolves the secret
+// references in an action's config. Each string of the config as written is
+// either rendered or resolved, never both, so a runtime value that starts
+// with secret: is not looked up and a secret is not run as a template.
+func renderTask(ctx context.Context, v interface{}, runtime map[string]interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		if strings.Contains(val, template.LateOpen) {
+			return template.RenderLate(val, runtime)
+		}
+		return secrets.ResolveRefs(ctx, val)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			r, err := renderTask(ctx, item, runtime)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			r, err := renderTask(ctx, item, runtime)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
 // extractRunOpts pulls retries and on_error from action config if present.
 func extractRunOpts(val interface{}) (int, string) {
 	m, ok := val.(map[string]interface{})
@@ -151,14 +230,20 @@ func runWithRetry(ctx context.Context, name string, action actions.Action, retri
 			return nil
 		}
 
+		if httpclient.IsPermanent(lastErr) {
+			deck.Warningf("Action %s attempt %d/%d failed permanently: %v (not retrying)", name, attempt, attempts, lastErr)
+			return lastErr
+		}
+
 		if attempt < attempts {
 			backoff := time.Duration(1<<uint(attempt-1)) * time.Second // 1s, 2s, 4s, 8s...
+			if ra := httpclient.RetryAfter(lastErr); ra > backoff {
+				backoff = ra
+			}
 			deck.Warningf("Action %s attempt %d/%d failed: %v (retrying in %v)", name, attempt, attempts, lastErr, backoff)
 
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(backoff):
+			if err := httpclient.Sleep(ctx, backoff); err != nil {
+				return err
 			}
 		}
 	}
@@ -200,7 +285,7 @@ func (r *Runner) checkPolicy(ctx context.Context, policyData interface{}) error
 			return fmt.Errorf("failed to create policy %s: %w", policyName, err)
 		}
 
-		if err := pol.Check(); err != nil {
+		if err := pol.Check(ctx); err != nil {
 			return fmt.Errorf("policy check failed for %s: %w", policyName, err)
 		}
 
@@ -210,49 +295,138 @@ func (r *Runner) checkPolicy(ctx context.Context, policyData interface{}) error
 	return nil
 }
 
+// includeWorkers bounds the number of config fetches in flight during a load.
+const includeWorkers = 8
+
 // LoadConfig recursively fetches and parses a config file, handling includes recursively.
 // It returns the flattened list of tasks without executing them.
 func (r *Runner) LoadConfig(ctx context.Context, url string) (TaskList, error) {
-	return r.loadConfigRecursive(ctx, url, make(map[string]bool))
+	st := &loadState{sem: make(chan struct{}, includeWorkers), seen: make(map[string][]string)}
+	return r.loadConfigRecursive(ctx, url, nil, st)
+}
+
+// loadState is shared by every file of one load.
+type loadState struct {
+	sem  chan struct{} // bounds fetches in flight
+	mu   sync.Mutex
+	seen map[string][]string // include chain by which each file was first reached
+}
+
+// visit records that url was reached through chain. A file may only be
+// included once, because each inclusion inherits different variables.
+func (st *loadState) visit(url string, chain []string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	prev, ok := st.seen[url]
+	if !ok {
+		st.seen[url] = chain
+		return nil
+	}
+	// Sort so the message does not depend on which fetch finished first.
+	chains := []string{formatChain(prev), formatChain(chain)}
+	sort.Strings(chains)
+	return fmt.Errorf("%s is included more than once: %s and %s", httpclient.RedactURL(url), chains[0], chains[1])
+}
+
+func formatChain(chain []string) string {
+	out := make([]string, len(chain))
+	for i, u := range chain {
+		out[i] = httpclient.RedactURL(u)
+	}
+	return strings.Join(out, " -> ")
 }
 
 // loadConfigRecursive fetches and parses a config file, handling includes recursively.
-// visited tracks URLs to prevent cycles.
-func (r *Runner) loadConfigRecursive(ctx context.Context, url string, visited map[string]bool) (TaskList, error) {
-	if visited[url] {
-		return nil, fmt.Errorf("circular dependency detected: %s", url)
+// Includes at each level are fetched concurrently, bounded by st.sem, and
+// their tasks are concatenated in declaration order. ancestors is the include
+// chain leading to url and is used to detect cycles.
+func (r *Runner) loadConfigRecursive(ctx context.Context, url string, ancestors []string, st *loadState) (TaskList, error) {
+	for _, a := range ancestors {
+		if a == url {
+			return nil, fmt.Errorf("circular dependency detected: %s", formatChain(append(append([]string{}, ancestors...), url)))
+		}
+	}
+	ancestors = append(ancestors[:len(ancestors):len(ancestors)], url)
+	if err := st.visit(url, ancestors); err != nil {
+		return nil, err
 	}
-	visited[url] = true
 
-	deck.Infof("Fetching config: %s", url)
+	deck.Infof("Fetching config: %s", httpclient.RedactURL(url))
+	// Prefer a free slot over a cancelled ctx, so a fetch that could start
+	// reports its own result.
+	select {
+	case st.sem <- struct{}{}:
+	default:
+		select {
+		case st.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
 	data, err := r.fetcher.Fetch(ctx, url)
+	<-st.sem // only the fetch holds a slot, so nested includes cannot deadlock
 	if err != nil {
-		return nil, fmt.Errorf("fetch failed for %s: %w", url, err)
+		return nil, fmt.Errorf("fetch failed for %s: %w", httpclient.RedactURL(url), err)
 	}
 
 	cfg, err := parseConfigData(data)
 	if err != nil {
-		return nil, fmt.Errorf("parse failed for %s: %w", url, err)
+		return nil, fmt.Errorf("parse failed for %s: %w", httpclient.RedactURL(url), err)
 	}
 
-	var allTasks TaskList
-
-	// Process includes first (prepend logic? or just standard flattening order)
-	// Usually includes are prepended or essentially "expanded in place".
-	// But since `include` is a sibling of `tasks` in our struct, it's ambiguous where they go relative to `tasks`.
-	// A common pattern is: Includes get processed, then local tasks.
-	for _, inc := range cfg.Includes {
-		absPath, err := resolvePath(url, inc)
+	// Includes are expanded before the file's own tasks.
+	paths := make([]string, len(cfg.Includes))
+	for i, inc := range cfg.Includes {
+		paths[i], err = resolvePath(url, inc)
 		if err != nil {
 			return nil, fmt.Errorf("failed to resolve path %s relative to %s: %w", inc, url, err)
 		}
-		subTasks, err := r.loadConfigRecursive(ctx, absPath, visited)
-		if err != nil {
-			return nil, err
-		}
-		allTasks = append(allTasks, subTasks...)
 	}
 
+	// Included files inherit this file's variables. The first failure
+	// cancels the fetches of the remaining includes.
+	childCtx, cancel := context.WithCancel(withVars(ctx, cfg.Vars.merge(inheritedVars(ctx))))
+	defer cancel()
+
+	results := make([]TaskList, len(paths))
+	errs := make([]error, len(paths))
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for i, p := range paths {
+		wg.Add(1)
+		go func(i int, p string) {
+			defer wg.Done()
+			results[i], errs[i] = r.loadConfigRecursive(childCtx, p, ancestors, st)
+			if errs[i] != nil {
+				once.Do(func() {
+					firstErr = errs[i]
+					cancel()
+				})
+			}
+		}(i, p)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		if ctx.Err() != nil {
+			return nil, firstErr
+		}
+		// Report the first failure in declaration order that was not caused
+		// by the cancellation.
+		for _, err := range errs {
+			if err != nil && !errors.Is(err, context.Canceled) {
+				return nil, err
+			}
+		}
+		return nil, firstErr
+	}
+	var allTasks TaskList
+	for i := range paths {
+		allTasks = append(allTasks, results[i]...)
+	}
 	allTasks = append(allTasks, cfg.Tasks...)
 	return allTasks, nil
 }
@@ -261,10 +435,19 @@ func (r *Runner) loadConfigRecursive(ctx context.Context, url string, visited ma
 // Handles both HTTP URLs and local file paths.
 func resolvePath(base, target string) (string, error) {
 	// If target is absolute, return it
-	if strings.HasPrefix(target, "http") || filepath.IsAbs(target) {
+	if strings.HasPrefix(target, "http") || strings.HasPrefix(target, bundle.Scheme) || filepath.IsAbs(target) {
 		return target, nil
 	}
 
+	// If base is inside a bundle, stay inside the same archive
+	if strings.HasPrefix(base, bundle.Scheme) {
+		archive, member, err := bundle.ParseURI(base)
+		if err != nil {
+			return "", err
+		}
+		return bundle.JoinURI(archive, path.Join(path.Dir(member), filepath.ToSlash(target))), nil
+	}
+
 	// If base is HTTP
 	if strings.HasPrefix(base, "http") {
 		u, err := url.Parse(base)
@@ -282,3 +465,67 @@ func resolvePath(base, target string) (string, error) {
 	baseDir := filepath.Dir(base)
 	return filepath.Join(baseDir, target), nil
 }
+
+// LocalFiles returns root and every config and template file it uses,
+// recursively, as local paths. Configs are not expanded; remote files are an error.
+// It is used to assemble bundles. Configs are read before templating, as the
+// facts of the machine building a bundle say nothing about the machines that
+// run it: a templated include or template file path is an error, and each
+// config must be valid YAML as written.
+func LocalFiles(root string) ([]string, error) {
+	var files []string
+	seen := map[string]bool{}
+	var walk func(p string) error
+	walk = func(p string) error {
+		if seen[p] {
+			return nil
+		}
+		seen[p] = true
+		files = append(files, p)
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		cfg, err := parseConfigData(data)
+		if err != nil {
+			return fmt.Errorf("parse failed for %s: %w (bundled configs are read before templating)", p, err)
+		}
+		for _, inc := range cfg.Includes {
+			if strings.Contains(inc, "{{") {
+				return fmt.Errorf("%s: cannot follow templated include %s; pass the files it may name as payloads", p, inc)
+			}
+			if strings.HasPrefix(inc, "http") || strings.HasPrefix(inc, bundle.Scheme) {
+				return fmt.Errorf("%s: cannot bundle remote include %s", p, httpclient.RedactURL(inc))
+			}
+			incPath, err := resolvePath(p, inc)
+			if err != nil {
+				return err
+			}
+			if err := walk(incPath); err != nil {
+				return err
+			}
+		}
+		for _, t := range cfg.Templates {
+			if strings.Contains(t, "{{") {
+				return fmt.Errorf("%s: cannot follow templated template file %s; pass the files it may name as payloads", p, t)
+			}
+			if strings.HasPrefix(t, "http") || strings.HasPrefix(t, bundle.Scheme) {
+				return fmt.Errorf("%s: cannot bundle remote template file %s", p, httpclient.RedactURL(t))
+			}
+			tPath, err := resolvePath(p, t)
+			if err != nil {
+				return err
+			}
+			if !seen[tPath] {
+				seen[tPath] = true
+				files = append(files, tPath)
+			}
+		}
+		return nil
+	}
+	if err := walk(root); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
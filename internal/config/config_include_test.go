@@ -3,9 +3,15 @@ package config
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/mjoliver/glazier-go/internal/actions"
+	"github.com/mjoliver/glazier-go/internal/bundle"
 )
 
 // ConfigIncludeMockAction helps us verify execution order.
@@ -184,3 +190,200 @@ func TestResolvePath(t *testing.T) {
 		}
 	}
 }
+
+func TestResolvePath_Bundle(t *testing.T) {
+	tests := []struct {
+		base   string
+		target string
+		want   string
+	}{
+		{"bundle://D:/b.zip#build.yaml", "sub.yaml", "bundle://D:/b.zip#sub.yaml"},
+		{"bundle://D:/b.zip#dir/build.yaml", "../common.yaml", "bundle://D:/b.zip#common.yaml"},
+		{"bundle://D:/b.zip#build.yaml", "http://example.com/x.yaml", "http://example.com/x.yaml"},
+	}
+	for _, tt := range tests {
+		got, err := resolvePath(tt.base, tt.target)
+		if err != nil || got != tt.want {
+			t.Errorf("resolvePath(%q, %q) = %q, %v, want %q", tt.base, tt.target, got, err, tt.want)
+		}
+	}
+}
+
+func TestRunner_Include_Bundle(t *testing.T) {
+	src := t.TempDir()
+	files := map[string]string{
+		"build.yaml":         "include:\n  - sub/sub.yaml\ntasks:\n  - mock.action: {id: 2}\n",
+		"sub/sub.yaml":       "include:\n  - ../common.yaml\ntasks:\n  - mock.action: {id: 1}\n",
+		"common.yaml":        "tasks:\n  - mock.action: {id: 0}\n",
+		"payloads/agent.msi": "binary",
+	}
+	for name, content := range files {
+		p := filepath.Join(src, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte(content), 0644)
+	}
+
+	root := filepath.Join(src, "build.yaml")
+	configs, err := LocalFiles(root)
+	if err != nil {
+		t.Fatalf("LocalFiles() error = %v", err)
+	}
+	if len(configs) != 3 {
+		t.Fatalf("LocalFiles() = %v, want 3 configs", configs)
+	}
+	out := filepath.Join(t.TempDir(), "bundle.zip")
+	if _, err := bundle.Build(out, src, root, configs[1:]); err != nil {
+		t.Fatalf("bundle.Build() error = %v", err)
+	}
+
+	runner := NewRunner(NewFetcher(nil))
+	tasks, err := runner.LoadConfig(context.Background(), "bundle://"+out+"#build.yaml")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	for i, task := range tasks {
+		if id := task["mock.action"].(map[string]interface{})["id"]; id != i {
+			t.Errorf("task %d has id %v", i, id)
+		}
+	}
+	if len(tasks) != 3 {
+		t.Errorf("got %d tasks, want 3", len(tasks))
+	}
+}
+
+// slowFetcher delays each fetch and records the peak number in flight.
+type slowFetcher struct {
+	MockFetcher
+	delay map[string]time.Duration
+
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (s *slowFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	s.mu.Lock()
+	s.inFlight++
+	if s.inFlight > s.peak {
+		s.peak = s.inFlight
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.inFlight--
+		s.mu.Unlock()
+	}()
+
+	d, ok := s.delay[url]
+	if !ok {
+		d = 20 * time.Millisecond
+	}
+	time.Sleep(d)
+	return s.MockFetcher.Fetch(ctx, url)
+}
+
+func TestRunner_Include_Concurrent(t *testing.T) {
+	files := map[string]string{}
+	root := "include:\n"
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("frag%02d.yaml", i)
+		root += "  - " + name + "\n"
+		files[name] = fmt.Sprintf("tasks:\n  - mock.action: {id: %d}\n", i)
+	}
+	files["root.yaml"] = root + "tasks:\n  - mock.action: {id: 20}\n"
+
+	f := &slowFetcher{MockFetcher: MockFetcher{Files: files}}
+	start := time.Now()
+	tasks, err := NewRunner(f).LoadConfig(context.Background(), "root.yaml")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if len(tasks) != 21 {
+		t.Fatalf("got %d tasks, want 21", len(tasks))
+	}
+	for i, task := range tasks {
+		if id := task["mock.action"].(map[string]interface{})["id"]; id != i {
+			t.Errorf("task %d has id %v, want declaration order", i, id)
+		}
+	}
+	if f.peak < 2 || f.peak > includeWorkers {
+		t.Errorf("peak concurrent fetches = %d, want between 2 and %d", f.peak, includeWorkers)
+	}
+	if elapsed := time.Since(start); elapsed > 20*20*time.Millisecond {
+		t.Errorf("LoadConfig took %v, includes were not fetched concurrently", elapsed)
+	}
+}
+
+func TestRunner_Include_FirstErrorInOrder(t *testing.T) {
+	f := &slowFetcher{
+		MockFetcher: MockFetcher{Files: map[string]string{
+			"root.yaml": "include:\n  - ok.yaml\n  - slow-missing.yaml\n  - fast-missing.yaml\n",
+			"ok.yaml":   "tasks:\n  - mock.action: {id: 0}\n",
+		}},
+		delay: map[string]time.Duration{"slow-missing.yaml": 50 * time.Millisecond, "fast-missing.yaml": 0},
+	}
+
+	for i := 0; i < 5; i++ {
+		_, err := NewRunner(f).LoadConfig(context.Background(), "root.yaml")
+		if err == nil || !strings.Contains(err.Error(), "slow-missing.yaml") {
+			t.Fatalf("LoadConfig() error = %v, want the first failing include in declaration order", err)
+		}
+	}
+}
+
+func TestRunner_Include_Diamond(t *testing.T) {
+	mock := &MockFetcher{Files: map[string]string{
+		"root.yaml":   "include:\n  - a.yaml\n  - b.yaml\n",
+		"a.yaml":      "include:\n  - common.yaml\ntasks:\n  - mock.action: {id: a}\n",
+		"b.yaml":      "include:\n  - common.yaml\ntasks:\n  - mock.action: {id: b}\n",
+		"common.yaml": "tasks:\n  - mock.action: {id: common}\n",
+	}}
+
+	// Each inclusion would inherit different vars, so a file reached twice
+	// is rejected rather than run twice or silently deduplicated.
+	for i := 0; i < 5; i++ {
+		_, err := NewRunner(mock).LoadConfig(context.Background(), "root.yaml")
+		want := "common.yaml is included more than once: root.yaml -> a.yaml -> common.yaml and root.yaml -> b.yaml -> common.yaml"
+		if err == nil || err.Error() != want {
+			t.Fatalf("LoadConfig() error = %v, want %q", err, want)
+		}
+	}
+
+	mock.Files["root.yaml"] = "include:\n  - a.yaml\n"
+	mock.Files["common.yaml"] = "include:\n  - root.yaml\n"
+	_, err := NewRunner(mock).LoadConfig(context.Background(), "root.yaml")
+	if err == nil || !strings.Contains(err.Error(), "root.yaml -> a.yaml -> common.yaml -> root.yaml") {
+		t.Errorf("LoadConfig() error = %v, want the include chain", err)
+	}
+}
+
+// blockingFetcher serves MockFetcher files but holds "hang.yaml" until the
+// fetch is cancelled.
+type blockingFetcher struct {
+	MockFetcher
+}
+
+func (b *blockingFetcher) Fetch(ctx context.Context, url string) ([]byte, error) {
+	if url == "hang.yaml" {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return b.MockFetcher.Fetch(ctx, url)
+}
+
+func TestRunner_Include_FailureCancelsSiblings(t *testing.T) {
+	f := &blockingFetcher{MockFetcher{Files: map[string]string{
+		"root.yaml": "include:\n  - hang.yaml\n  - missing.yaml\n",
+	}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := NewRunner(f).LoadConfig(ctx, "root.yaml")
+	if ctx.Err() != nil {
+		t.Fatal("LoadConfig() waited for a sibling after an include failed")
+	}
+	if err == nil || !strings.Contains(err.Error(), "missing.yaml") {
+		t.Errorf("LoadConfig() error = %v, want the failing include rather than the cancelled one", err)
+	}
+}
@@ -3,18 +3,26 @@ package config
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
+
+	"github.com/mjoliver/glazier-go/internal/actions"
+	"github.com/mjoliver/glazier-go/internal/httpclient"
 )
 
 // MockAction for testing retries
 type MockAction struct {
 	FailCount int
 	Calls     int
+	Err       error // returned instead of a generic failure when set
 }
 
 func (m *MockAction) Run(ctx context.Context) error {
 	m.Calls++
 	if m.Calls <= m.FailCount {
+		if m.Err != nil {
+			return m.Err
+		}
 		return fmt.Errorf("mock failure %d", m.Calls)
 	}
 	return nil
@@ -110,4 +118,140 @@ func TestRunWithRetry(t *testing.T) {
 			t.Errorf("runWithRetry() calls = %d, want 3", a.Calls)
 		}
 	})
+
+	t.Run("permanent error not retried", func(t *testing.T) {
+		a := &MockAction{FailCount: 5, Err: fmt.Errorf("download: %w", &httpclient.StatusError{StatusCode: 404})}
+		err := runWithRetry(ctx, "test", a, 3)
+		if err == nil {
+			t.Error("runWithRetry() expected error, got nil")
+		}
+		if a.Calls != 1 {
+			t.Errorf("runWithRetry() calls = %d, want 1", a.Calls)
+		}
+	})
+}
+
+func TestRunner_Events(t *testing.T) {
+	mock := &MockFetcher{
+		Files: map[string]string{
+			"main.yaml": `
+tasks:
+  - mock.action: {id: 1}
+  - mock.action: {id: 2}
+`,
+		},
+	}
+
+	var events []actions.Event
+	runner := NewRunner(mock)
+	runner.SetEventSink(func(ev actions.Event) { events = append(events, ev) })
+	if err := runner.Start(context.Background(), "main.yaml"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	want := []struct {
+		task int
+		typ  string
+	}{{1, "start"}, {1, "done"}, {2, "start"}, {2, "done"}}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		if events[i].Task != w.task || events[i].Type != w.typ || events[i].Action != "mock.action" {
+			t.Errorf("event %d = %+v, want task %d %s", i, events[i], w.task, w.typ)
+		}
+	}
+}
+
+// secretAction fails with its password in the error, as a careless action might.
+type secretAction struct{ password string }
+
+func (a *secretAction) Run(ctx context.Context) error {
+	return fmt.Errorf("login rejected for password %s", a.password)
+}
+func (a *secretAction) Validate() error { return nil }
+
+func TestRunner_SecretRefs(t *testing.T) {
+	t.Setenv("GLAZIER_TEST_JOIN_PASSWORD", "hunter2-hunter2")
+	var got string
+	actions.Register("mock.secret", func(ctx context.Context, cfg interface{}) (actions.Action, error) {
+		got, _ = cfg.(map[string]interface{})["password"].(string)
+		return &secretAction{password: got}, nil
+	})
+	defer delete(actions.Registry, "mock.secret")
+
+	mock := &MockFetcher{
+		Files: map[string]string{
+			"main.yaml": `
+tasks:
+  - mock.secret: {password: "secret:env:GLAZIER_TEST_JOIN_PASSWORD", on_error: continue}
+`,
+		},
+	}
+	var events []actions.Event
+	runner := NewRunner(mock)
+	runner.SetEventSink(func(ev actions.Event) { events = append(events, ev) })
+	if err := runner.Start(context.Background(), "main.yaml"); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if got != "hunter2-hunter2" {
+		t.Errorf("action password = %q, want the resolved secret", got)
+	}
+	for _, ev := range events {
+		if strings.Contains(ev.Message, "hunter2") {
+			t.Errorf("event %s leaks the secret: %q", ev.Type, ev.Message)
+		}
+	}
+
+	if err := Validate(context.Background(), TaskList{{"mock.action": map[string]interface{}{"password": "secret:vault:x"}}}); err == nil {
+		t.Error("Validate() should reject an unknown secret provider")
+	}
+}
+
+// publishAction stores its value as a runtime variable, like registry.get.
+type publishAction struct{ name, value string }
+
+func (a *publishAction) Run(ctx context.Context) error {
+	actions.SetRuntimeVar(ctx, a.name, a.value)
+	return nil
+}
+func (a *publishAction) Validate() error { return nil }
+
+func TestRunner_LateBoundTemplates(t *testing.T) {
+	t.Setenv("GLAZIER_TEST_LATE_SECRET", "hunter2-hunter2")
+	var got []interface{}
+	actions.Register("mock.publish", func(ctx context.Context, cfg interface{}) (actions.Action, error) {
+		m := cfg.(map[string]interface{})
+		return &publishAction{m["name"].(string), m["value"].(string)}, nil
+	})
+	actions.Register("mock.record", func(ctx context.Context, cfg interface{}) (actions.Action, error) {
+		got = append(got, cfg.(map[string]interface{})["id"])
+		return &MockAction{}, nil
+	})
+	defer delete(actions.Registry, "mock.publish")
+	defer delete(actions.Registry, "mock.record")
+
+	mock := &MockFetcher{
+		Files: map[string]string{
+			"main.yaml": `
+tasks:
+  - mock.publish: {name: dir, value: 'C:\Apps'}
+  - mock.record: {id: '${{ .Runtime.dir }}\agent.msi'}
+  - mock.publish: {name: dir, value: 'D:\Apps'}
+  - mock.record: {id: '${{ .Runtime.dir | lower }}'}
+  - mock.publish: {name: token, value: '${{ "secret:env:GLAZIER_TEST_LATE_SECRET" }}'}
+  - mock.record: {id: '${{ .Runtime.token }}'}
+  - mock.record: {id: '${{ .Runtime.unset }}'}
+`,
+		},
+	}
+	err := NewRunner(mock).Start(context.Background(), "main.yaml")
+	if err == nil || !strings.Contains(err.Error(), "undefined variable .Runtime.unset") {
+		t.Errorf("Start() error = %v, want undefined runtime variable", err)
+	}
+	// A runtime value is never looked up as a secret reference.
+	want := []interface{}{`C:\Apps\agent.msi`, `d:\apps`, "secret:env:GLAZIER_TEST_LATE_SECRET"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("rendered ids = %q, want %q", got, want)
+	}
 }
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/deck"
+
+	"github.com/mjoliver/glazier-go/internal/bundle"
+	"github.com/mjoliver/glazier-go/internal/httpclient"
+)
+
+// WellKnownNames are tried, in order, when the config root is a directory or
+// a base URL.
+var WellKnownNames = []string{"build.yaml", "config.yaml"}
+
+// RootEnv names the environment variable consulted when no root is given.
+const RootEnv = "GLAZIER_CONFIG_ROOT"
+
+// MarkerFile identifies installation media. If it is not empty, its first
+// line is the config path relative to the volume; otherwise the volume root
+// is searched for WellKnownNames.
+const MarkerFile = "glazier.marker"
+
+// listVolumes returns the mount points searched for MarkerFile. It is a
+// variable so tests can substitute fake volumes.
+var listVolumes = platformVolumes
+
+// Discover turns the -config_root_path value into the path of a root config.
+// Files, bundle URIs and config URLs are returned as-is. Directories and base
+// URLs are searched for WellKnownNames. If root is empty, RootEnv is read,
+// then mounted volumes are searched for MarkerFile. Every candidate is logged.
+func Discover(ctx context.Context, root string) (string, error) {
+	if root == "" {
+		if env := os.Getenv(RootEnv); env != "" {
+			deck.Infof("Config discovery: using %s from %s", httpclient.RedactURL(env), RootEnv)
+			root = env
+		}
+	}
+	if root == "" {
+		return discoverMedia(ctx)
+	}
+
+	switch {
+	case strings.HasPrefix(root, bundle.Scheme):
+		return chosen(root)
+	case strings.HasPrefix(root, "http"):
+		if isConfigName(root) {
+			return chosen(root)
+		}
+		return discoverURL(ctx, root)
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", fmt.Errorf("config root %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return chosen(root)
+	}
+	if p, ok := searchDir(root); ok {
+		return chosen(p)
+	}
+	return "", fmt.Errorf("no %s found in %s", strings.Join(WellKnownNames, " or "), root)
+}
+
+func chosen(p string) (string, error) {
+	deck.Infof("Config discovery: using %s", httpclient.RedactURL(p))
+	return p, nil
+}
+
+func isConfigName(p string) bool {
+	if i := strings.IndexAny(p, "?#"); i >= 0 {
+		p = p[:i]
+	}
+	ext := strings.ToLower(filepath.Ext(p))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// searchDir looks for WellKnownNames in dir.
+func searchDir(dir string) (string, bool) {
+	for _, name := range WellKnownNames {
+		p := filepath.Join(dir, name)
+		deck.Infof("Config discovery: checking %s", p)
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// discoverURL probes WellKnownNames under a base URL.
+func discoverURL(ctx context.Context, base string) (string, error) {
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	client := httpclient.New(10 * time.Second)
+	for _, name := range WellKnownNames {
+		candidate, err := resolvePath(base, name)
+		if err != nil {
+			return "", err
+		}
+		deck.Infof("Config discovery: checking %s", httpclient.RedactURL(candidate))
+		req, err := http.NewRequestWithContext(ctx, "GET", candidate, nil)
+		if err != nil {
+			return "", httpclient.RedactError(err)
+		}
+		resp, err := httpclient.Do(ctx, client, req, httpclient.RetryPolicy{Attempts: 1})
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			deck.Infof("Config discovery: %s: %v", httpclient.RedactURL(candidate), err)
+			continue
+		}
+		resp.Body.Close()
+		return chosen(candidate)
+	}
+	return "", fmt.Errorf("no %s found under %s", strings.Join(WellKnownNames, " or "), httpclient.RedactURL(base))
+}
+
+// discoverMedia searches mounted volumes for MarkerFile.
+func discoverMedia(ctx context.Context) (string, error) {
+	volumes := listVolumes()
+	deck.Infof("Config discovery: no root given, searching %d volumes for %s", len(volumes), MarkerFile)
+	for _, vol := range volumes {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		marker := filepath.Join(vol, MarkerFile)
+		deck.Infof("Config discovery: checking %s", marker)
+		data, err := os.ReadFile(marker)
+		if err != nil {
+			continue
+		}
+		if line, _, _ := strings.Cut(strings.TrimSpace(string(data)), "\n"); strings.TrimSpace(line) != "" {
+			return chosen(filepath.Join(vol, filepath.FromSlash(strings.TrimSpace(line))))
+		}
+		if p, ok := searchDir(vol); ok {
+			return chosen(p)
+		}
+		deck.Warningf("Config discovery: %s found but %s has no config", marker, vol)
+	}
+	return "", fmt.Errorf("no config root given: set -config_root_path or %s, or attach media containing %s", RootEnv, MarkerFile)
+}
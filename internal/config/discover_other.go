@@ -0,0 +1,15 @@
+//go:build !windows
+
+package config
+
+import "path/filepath"
+
+// platformVolumes returns removable media mount points.
+func platformVolumes() []string {
+	var vols []string
+	for _, pattern := range []string{"/media/*", "/media/*/*", "/run/media/*/*", "/mnt/*"} {
+		matches, _ := filepath.Glob(pattern)
+		vols = append(vols, matches...)
+	}
+	return vols
+}
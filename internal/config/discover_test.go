@@ -0,0 +1,109 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiscover_Paths(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("tasks: []"), 0644)
+	empty := t.TempDir()
+
+	tests := []struct {
+		name    string
+		root    string
+		want    string
+		wantErr bool
+	}{
+		{"file", filepath.Join(dir, "config.yaml"), filepath.Join(dir, "config.yaml"), false},
+		{"directory", dir, filepath.Join(dir, "config.yaml"), false},
+		{"empty directory", empty, "", true},
+		{"missing", filepath.Join(dir, "nope"), "", true},
+		{"bundle", "bundle://D:/b.zip#build.yaml", "bundle://D:/b.zip#build.yaml", false},
+		{"config url", "https://example.com/build.yaml", "https://example.com/build.yaml", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Discover(context.Background(), tt.root)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Discover() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Discover() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscover_BaseURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/configs/config.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("tasks: []"))
+	}))
+	defer server.Close()
+
+	got, err := Discover(context.Background(), server.URL+"/configs")
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+	if want := server.URL + "/configs/config.yaml"; got != want {
+		t.Errorf("Discover() = %q, want %q", got, want)
+	}
+
+	if _, err := Discover(context.Background(), server.URL+"/empty/"); err == nil {
+		t.Error("Discover() expected error for a base URL with no config")
+	}
+}
+
+func TestDiscover_NoRoot(t *testing.T) {
+	orig := listVolumes
+	defer func() { listVolumes = orig }()
+
+	plain, usb, pointed := t.TempDir(), t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(plain, "build.yaml"), []byte("tasks: []"), 0644) // no marker
+	os.WriteFile(filepath.Join(usb, MarkerFile), nil, 0644)
+	os.WriteFile(filepath.Join(usb, "build.yaml"), []byte("tasks: []"), 0644)
+	os.MkdirAll(filepath.Join(pointed, "glazier"), 0755)
+	os.WriteFile(filepath.Join(pointed, MarkerFile), []byte("glazier/site.yaml\n"), 0644)
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv(RootEnv, filepath.Join(usb, "build.yaml"))
+		listVolumes = func() []string { t.Fatal("volumes searched despite env"); return nil }
+		got, err := Discover(context.Background(), "")
+		if err != nil || got != filepath.Join(usb, "build.yaml") {
+			t.Errorf("Discover() = %q, %v", got, err)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		volumes []string
+		want    string
+		wantErr bool
+	}{
+		{"marker with well-known name", []string{plain, usb}, filepath.Join(usb, "build.yaml"), false},
+		{"marker with path", []string{plain, pointed}, filepath.Join(pointed, "glazier", "site.yaml"), false},
+		{"no marker", []string{plain}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(RootEnv, "")
+			listVolumes = func() []string { return tt.volumes }
+			got, err := Discover(context.Background(), "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Discover() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Discover() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package config
+
+import "os"
+
+// platformVolumes returns the root of every mounted drive letter, skipping A:
+// and B: to avoid probing floppy controllers.
+func platformVolumes() []string {
+	var vols []string
+	for c := 'C'; c <= 'Z'; c++ {
+		root := string(c) + `:\`
+		if _, err := os.Stat(root); err == nil {
+			vols = append(vols, root)
+		}
+	}
+	return vols
+}
@@ -6,9 +6,14 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/mjoliver/glazier-go/internal/bundle"
+	"github.com/mjoliver/glazier-go/internal/httpclient"
+	"github.com/mjoliver/glazier-go/internal/secrets"
 	"github.com/mjoliver/glazier-go/internal/template"
 )
 
@@ -20,6 +25,14 @@ type FetcherInterface interface {
 // Fetcher retrieves configuration files.
 type Fetcher struct {
 	buildInfo *template.BuildInfo
+
+	strict bool
+
+	mu           sync.Mutex
+	conflicts    map[string]VarConflict
+	tables       map[string]*table
+	partialFiles map[string]*partialFile
+	used         map[string][]string
 }
 
 // NewFetcher creates a new Fetcher with optional template support.
@@ -27,73 +40,140 @@ func NewFetcher(buildInfo *template.BuildInfo) *Fetcher {
 	return &Fetcher{buildInfo: buildInfo}
 }
 
+// SetStrict makes references to undefined map keys, such as
+// {{.Vars.missing}}, an error instead of rendering "<no value>".
+func (f *Fetcher) SetStrict(strict bool) {
+	f.strict = strict
+}
+
 // Fetch retrieves the content at the given path/URL.
 func (f *Fetcher) Fetch(ctx context.Context, path string) ([]byte, error) {
-	var data []byte
-	var err error
+	data, err := f.read(ctx, path)
+	if err != nil {
+		return nil, err
+	}
 
-	if strings.HasPrefix(path, "http") {
-		data, err = f.fetchRemote(ctx, path)
-	} else {
-		data, err = f.fetchLocal(path)
+	// Apply template processing if BuildInfo is available
+	if f.buildInfo == nil {
+		return data, nil
+	}
+	own, err := extractVars(data)
+	if err != nil {
+		return nil, err
 	}
+	inherited := inheritedVars(ctx)
+	f.recordConflicts(conflicts(path, own, inherited, f.buildInfo.Vars))
 
+	partials, err := f.partials(ctx, path, data)
 	if err != nil {
 		return nil, err
 	}
 
-	// Apply template processing if BuildInfo is available
-	return template.Process(data, f.buildInfo)
+	info := *f.buildInfo
+	info.Vars = own.merge(inherited, f.buildInfo.Vars)
+	opts := template.Options{
+		Name:     httpclient.RedactURL(path),
+		Strict:   f.strict,
+		Partials: partials,
+		Funcs: map[string]interface{}{
+			"lookup": f.lookupFunc(ctx, path),
+			"secret": secrets.TemplateFunc(ctx),
+		},
+	}
+	out, err := template.ProcessWith(data, &info, opts)
+	if err != nil {
+		return nil, err
+	}
+	if used, err := template.Variables(data, opts); err == nil {
+		f.mu.Lock()
+		if f.used == nil {
+			f.used = map[string][]string{}
+		}
+		f.used[path] = used
+		f.mu.Unlock()
+	}
+	return out, nil
 }
 
-func (f *Fetcher) fetchLocal(path string) ([]byte, error) {
-	return os.ReadFile(path)
+// read retrieves the raw content at the given path/URL.
+func (f *Fetcher) read(ctx context.Context, path string) ([]byte, error) {
+	if strings.HasPrefix(path, bundle.Scheme) {
+		return bundle.ReadURI(path)
+	}
+	if strings.HasPrefix(path, "http") {
+		return f.fetchRemote(ctx, path)
+	}
+	return f.fetchLocal(path)
 }
 
-func (f *Fetcher) fetchRemote(ctx context.Context, url string) ([]byte, error) {
-	var data []byte
-	var lastErr error
-
-	// Exponential backoff configuration
-	maxRetries := 3
-	baseDelay := 1 * time.Second
-	client := &http.Client{Timeout: 30 * time.Second}
-
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
-		}
+func (f *Fetcher) recordConflicts(cs []VarConflict) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.conflicts == nil {
+		f.conflicts = map[string]VarConflict{}
+	}
+	for _, c := range cs {
+		f.conflicts[c.String()] = c
+	}
+}
 
-		resp, err := client.Do(req)
-		if err != nil {
-			lastErr = err
-			if attempt < maxRetries-1 {
-				delay := baseDelay * time.Duration(1<<uint(attempt)) // 1s, 2s, 4s
-				time.Sleep(delay)
-				continue
-			}
-			return nil, fmt.Errorf("failed to fetch URL after %d attempts: %w", maxRetries, err)
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			lastErr = fmt.Errorf("bad status code: %d", resp.StatusCode)
-			if attempt < maxRetries-1 {
-				delay := baseDelay * time.Duration(1<<uint(attempt))
-				time.Sleep(delay)
-				continue
-			}
-			return nil, lastErr
+// VarConflicts returns every config variable that was overridden with a
+// different value during loading, sorted by file and name.
+func (f *Fetcher) VarConflicts() []VarConflict {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]VarConflict, 0, len(f.conflicts))
+	for _, c := range f.conflicts {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].File != out[j].File {
+			return out[i].File < out[j].File
 		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
 
-		data, err = io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read response: %w", err)
-		}
+// TemplateUsage lists the template variables a config file uses.
+type TemplateUsage struct {
+	File      string
+	Variables []string
+}
 
-		return data, nil
+// TemplateVariables returns the variables used by every config fetched so
+// far, sorted by file.
+func (f *Fetcher) TemplateVariables() []TemplateUsage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]TemplateUsage, 0, len(f.used))
+	for file, vars := range f.used {
+		out = append(out, TemplateUsage{file, vars})
 	}
+	sort.Slice(out, func(i, j int) bool { return out[i].File < out[j].File })
+	return out
+}
 
-	return nil, fmt.Errorf("failed after %d retries: %w", maxRetries, lastErr)
+func (f *Fetcher) fetchLocal(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func (f *Fetcher) fetchRemote(ctx context.Context, url string) ([]byte, error) {
+	client := httpclient.New(30 * time.Second)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", httpclient.RedactError(err))
+	}
+
+	resp, err := httpclient.Do(ctx, client, req, httpclient.DefaultRetryPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return data, nil
 }
@@ -5,9 +5,13 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/mjoliver/glazier-go/internal/template"
 )
 
 func TestFetcher_Fetch(t *testing.T) {
@@ -145,3 +149,42 @@ func TestFetcher_fetchRemote_ContextCanceled(t *testing.T) {
 		t.Errorf("fetchRemote() error = %v, want context cancellation error", err)
 	}
 }
+
+func TestFetcher_fetchRemote_NotFoundNotRetried(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	f := NewFetcher(nil)
+	_, err := f.fetchRemote(context.Background(), server.URL)
+	if err == nil {
+		t.Fatal("fetchRemote() expected error for 404, got nil")
+	}
+	if attempts != 1 {
+		t.Errorf("fetchRemote() made %d attempts for 404, want 1", attempts)
+	}
+}
+
+func TestFetcher_StrictAndVariables(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "build.yaml")
+	os.WriteFile(path, []byte("tasks:\n  - mock.action: {id: \"{{.Hostname}}-{{.Vars.site}}\"}\n"), 0644)
+
+	f := NewFetcher(&template.BuildInfo{Hostname: "ws1"})
+	if _, err := f.Fetch(context.Background(), path); err != nil {
+		t.Fatalf("lenient Fetch() error = %v", err)
+	}
+	got := f.TemplateVariables()
+	if len(got) != 1 || got[0].File != path || strings.Join(got[0].Variables, ",") != ".Hostname,.Vars.site" {
+		t.Errorf("TemplateVariables() = %+v", got)
+	}
+
+	f.SetStrict(true)
+	_, err := f.Fetch(context.Background(), path)
+	if err == nil || !strings.Contains(err.Error(), path+":2:") || !strings.Contains(err.Error(), "undefined variable .Vars.site") {
+		t.Errorf("strict Fetch() error = %v, want file, line and variable", err)
+	}
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/google/deck"
+	"github.com/mjoliver/glazier-go/internal/httpclient"
+	"gopkg.in/yaml.v3"
+)
+
+// table is a lookup table, loaded at most once per Fetcher.
+type table struct {
+	once sync.Once
+	rows []map[string]interface{}
+	err  error
+}
+
+// lookupFunc returns the lookup template function for the config at base:
+//
+//	{{(lookup "assets.csv" "serial" .Facts.serial).ou}}
+//	{{(lookup "assets.csv" "mac" .Facts.macs "default").image}}
+//
+// The table is resolved relative to base and fetched like an include. It
+// returns the first row whose column equals key, ignoring case and
+// surrounding space. key may be a list, in which case any element matches.
+// If nothing matches, the row for the optional default key is returned, and
+// failing that an empty row, whose fields can be filled in with default.
+func (f *Fetcher) lookupFunc(ctx context.Context, base string) func(string, string, interface{}, ...string) (map[string]interface{}, error) {
+	return func(name, column string, key interface{}, def ...string) (map[string]interface{}, error) {
+		loc, err := resolvePath(base, name)
+		if err != nil {
+			return nil, fmt.Errorf("lookup %s: %w", name, err)
+		}
+		rows, err := f.table(ctx, loc)
+		if err != nil {
+			return nil, fmt.Errorf("lookup %s: %w", name, err)
+		}
+		if len(rows) > 0 && !hasColumn(rows, column) {
+			return nil, fmt.Errorf("lookup %s: no column %q", name, column)
+		}
+		if row := findRow(rows, column, lookupKeys(key)); row != nil {
+			return row, nil
+		}
+		if len(def) > 0 {
+			if row := findRow(rows, column, def[:1]); row != nil {
+				return row, nil
+			}
+		}
+		return map[string]interface{}{}, nil
+	}
+}
+
+// table returns the parsed table at loc, loading it on first use so that
+// every config in a run shares one copy.
+func (f *Fetcher) table(ctx context.Context, loc string) ([]map[string]interface{}, error) {
+	f.mu.Lock()
+	if f.tables == nil {
+		f.tables = map[string]*table{}
+	}
+	t, ok := f.tables[loc]
+	if !ok {
+		t = &table{}
+		f.tables[loc] = t
+	}
+	f.mu.Unlock()
+
+	t.once.Do(func() {
+		data, err := f.read(ctx, loc)
+		if err != nil {
+			t.err = err
+			return
+		}
+		t.rows, t.err = parseTable(loc, data)
+		if t.err == nil {
+			deck.Infof("Loaded lookup table %s (%d rows)", httpclient.RedactURL(loc), len(t.rows))
+		}
+	})
+	return t.rows, t.err
+}
+
+// parseTable decodes a CSV file with a header row, or a YAML or JSON list
+// of maps, based on the file extension.
+func parseTable(loc string, data []byte) ([]map[string]interface{}, error) {
+	p, _, _ := strings.Cut(loc, "?")
+	switch ext := strings.ToLower(path.Ext(p)); ext {
+	case ".csv":
+		return parseCSV(data)
+	case ".yaml", ".yml", ".json":
+		var rows []map[string]interface{}
+		if err := yaml.Unmarshal(data, &rows); err != nil {
+			return nil, fmt.Errorf("table must be a list of maps: %w", err)
+		}
+		return rows, nil
+	default:
+		return nil, fmt.Errorf("unsupported table format %q (want .csv, .yaml or .json)", ext)
+	}
+}
+
+func parseCSV(data []byte) ([]map[string]interface{}, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))) // spreadsheet exports often start with a BOM
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	header := records[0]
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	rows := make([]map[string]interface{}, 0, len(records)-1)
+	for _, rec := range records[1:] {
+		row := make(map[string]interface{}, len(header))
+		for i, col := range header {
+			if i < len(rec) {
+				row[col] = strings.TrimSpace(rec[i])
+			} else {
+				row[col] = ""
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func hasColumn(rows []map[string]interface{}, column string) bool {
+	for _, row := range rows {
+		if _, ok := row[column]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupKeys flattens a lookup key, which may be a single value or a list.
+func lookupKeys(key interface{}) []string {
+	var keys []string
+	switch v := key.(type) {
+	case nil:
+	case []string:
+		keys = v
+	case []interface{}:
+		for _, k := range v {
+			keys = append(keys, fmt.Sprint(k))
+		}
+	default:
+		keys = []string{fmt.Sprint(v)}
+	}
+	return keys
+}
+
+func findRow(rows []map[string]interface{}, column string, keys []string) map[string]interface{} {
+	for _, row := range rows {
+		v, ok := row[column]
+		if !ok || v == nil {
+			continue
+		}
+		cell := strings.TrimSpace(fmt.Sprint(v))
+		for _, k := range keys {
+			if k = strings.TrimSpace(k); k != "" && strings.EqualFold(cell, k) {
+				return row
+			}
+		}
+	}
+	return nil
+}
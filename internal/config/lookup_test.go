@@ -0,0 +1,121 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mjoliver/glazier-go/internal/template"
+)
+
+const assetsCSV = "\xef\xbb\xbfserial, mac, hostname, ou\n" +
+	"ABC123, AA:BB:CC:DD:EE:01, lab-01, OU=Lab\n" +
+	"DEF456, aa:bb:cc:dd:ee:02, lab-02, OU=Staff\n" +
+	"default, , new-host, OU=Staging\n"
+
+func TestParseTable(t *testing.T) {
+	rows, err := parseTable("assets.csv", []byte(assetsCSV))
+	if err != nil || len(rows) != 3 || rows[0]["serial"] != "ABC123" || rows[1]["ou"] != "OU=Staff" {
+		t.Errorf("parseTable(csv) = %v, %v", rows, err)
+	}
+
+	rows, err = parseTable("https://example.com/assets.yaml?v=2", []byte("- serial: ABC123\n  ring: 1\n"))
+	if err != nil || len(rows) != 1 || rows[0]["ring"] != 1 {
+		t.Errorf("parseTable(yaml) = %v, %v", rows, err)
+	}
+
+	for name, data := range map[string]string{
+		"assets.txt":  "serial\nABC123\n",
+		"assets.yaml": "serial: ABC123\n",
+		"assets.csv":  "a,\"b\n",
+	} {
+		if _, err := parseTable(name, []byte(data)); err == nil {
+			t.Errorf("parseTable(%s) expected error", name)
+		}
+	}
+}
+
+func TestFetcher_Lookup(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "assets.csv"), []byte(assetsCSV), 0644)
+
+	info := &template.BuildInfo{Facts: map[string]interface{}{
+		"serial": "abc123",
+		"macs":   []interface{}{"00:00:00:00:00:00", "AA:BB:CC:DD:EE:02"},
+	}}
+	tests := []struct {
+		name    string
+		tmpl    string
+		want    string
+		wantErr bool
+	}{
+		{"by serial", `{{(lookup "assets.csv" "serial" .Facts.serial).ou}}`, "OU=Lab", false},
+		{"any mac", `{{(lookup "assets.csv" "mac" .Facts.macs).hostname}}`, "lab-02", false},
+		{"default row", `{{(lookup "assets.csv" "serial" "ZZZ" "default").ou}}`, "OU=Staging", false},
+		{"no match", `{{(lookup "assets.csv" "serial" "ZZZ").ou | default "none"}}`, "none", false},
+		{"missing key", `{{(lookup "assets.csv" "serial" .Facts.absent).ou | default "none"}}`, "none", false},
+		{"unknown column", `{{(lookup "assets.csv" "asset_tag" "x").ou}}`, "", true},
+		{"missing table", `{{(lookup "nope.csv" "serial" "x").ou}}`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "config.yaml")
+			os.WriteFile(path, []byte(tt.tmpl), 0644)
+			got, err := NewFetcher(info).Fetch(context.Background(), path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && strings.TrimSpace(string(got)) != tt.want {
+				t.Errorf("Fetch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetcher_LookupCachedAcrossIncludes(t *testing.T) {
+	var tableHits int32
+	files := map[string]string{
+		"/root.yaml": `
+include:
+  - sub/child.yaml
+tasks:
+  - mock.action: {id: "{{(lookup "assets.csv" "serial" "ABC123").hostname}}"}
+`,
+		"/sub/child.yaml": `
+tasks:
+  - mock.action: {id: "{{(lookup "../assets.csv" "serial" "DEF456").hostname}}"}
+`,
+		"/assets.csv": assetsCSV,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/assets.csv" {
+			atomic.AddInt32(&tableHits, 1)
+		}
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	tasks, err := NewRunner(NewFetcher(&template.BuildInfo{})).LoadConfig(context.Background(), server.URL+"/root.yaml")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	want := []string{"lab-02", "lab-01"}
+	for i, task := range tasks {
+		if id := task["mock.action"].(map[string]interface{})["id"]; id != want[i] {
+			t.Errorf("task %d id = %v, want %s", i, id, want[i])
+		}
+	}
+	if tableHits != 1 {
+		t.Errorf("assets.csv fetched %d times, want 1", tableHits)
+	}
+}
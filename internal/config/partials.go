@@ -0,0 +1,59 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mjoliver/glazier-go/internal/httpclient"
+	"github.com/mjoliver/glazier-go/internal/template"
+)
+
+// partialFile is a template file, read at most once per Fetcher.
+type partialFile struct {
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// partials loads the template files listed under a config's top-level
+// templates: key, resolved relative to the config.
+func (f *Fetcher) partials(ctx context.Context, path string, data []byte) ([]template.Partial, error) {
+	var doc struct {
+		Templates []string `yaml:"templates"`
+	}
+	if err := extractBlock(data, "templates", &doc); err != nil {
+		return nil, err
+	}
+	var out []template.Partial
+	for _, name := range doc.Templates {
+		loc, err := resolvePath(path, name)
+		if err != nil {
+			return nil, fmt.Errorf("templates: %s: %w", name, err)
+		}
+		body, err := f.partialFile(ctx, loc)
+		if err != nil {
+			return nil, fmt.Errorf("templates: %s: %w", name, err)
+		}
+		out = append(out, template.Partial{Name: httpclient.RedactURL(loc), Data: body})
+	}
+	return out, nil
+}
+
+func (f *Fetcher) partialFile(ctx context.Context, loc string) ([]byte, error) {
+	f.mu.Lock()
+	if f.partialFiles == nil {
+		f.partialFiles = map[string]*partialFile{}
+	}
+	p, ok := f.partialFiles[loc]
+	if !ok {
+		p = &partialFile{}
+		f.partialFiles[loc] = p
+	}
+	f.mu.Unlock()
+
+	p.once.Do(func() {
+		p.data, p.err = f.read(ctx, loc)
+	})
+	return p.data, p.err
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mjoliver/glazier-go/internal/template"
+)
+
+const commonPartials = `
+{{define "tagged"}}
+- mock.action:
+    id: "{{.Vars.prefix}}-{{.Hostname}}"
+{{end}}
+`
+
+func TestFetcher_Partials(t *testing.T) {
+	var hits int32
+	files := map[string]string{
+		"/root.yaml": `
+templates:
+  - partials/common.yaml
+vars:
+  prefix: root
+include:
+  - sub/child.yaml
+tasks:
+  {{template "tagged" .}}
+`,
+		"/sub/child.yaml": `
+templates: [../partials/common.yaml]
+vars:
+  prefix: child
+tasks:
+  {{- template "tagged" .}}
+`,
+		"/partials/common.yaml": commonPartials,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/partials/common.yaml" {
+			atomic.AddInt32(&hits, 1)
+		}
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	tasks, err := NewRunner(NewFetcher(&template.BuildInfo{Hostname: "ws1"})).LoadConfig(context.Background(), server.URL+"/root.yaml")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	// child's own prefix is a default that root overrides
+	want := []string{"root-ws1", "root-ws1"}
+	if len(tasks) != len(want) {
+		t.Fatalf("got %d tasks, want %d", len(tasks), len(want))
+	}
+	for i, task := range tasks {
+		if id := task["mock.action"].(map[string]interface{})["id"]; id != want[i] {
+			t.Errorf("task %d id = %v, want %s", i, id, want[i])
+		}
+	}
+	if hits != 1 {
+		t.Errorf("partials fetched %d times, want 1", hits)
+	}
+}
+
+func TestFetcher_PartialsMissing(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "build.yaml")
+	os.WriteFile(path, []byte("templates: [nope.yaml]\ntasks: []\n"), 0644)
+	if _, err := NewFetcher(&template.BuildInfo{}).Fetch(context.Background(), path); err == nil {
+		t.Error("Fetch() expected error for a missing template file")
+	}
+}
+
+func TestLocalFiles_Templated(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"include", "include: ['{{ .Facts.model }}.yaml']\n", "cannot follow templated include {{ .Facts.model }}.yaml"},
+		{"template file", "templates: ['partials/{{ .Vars.site }}.yaml']\n", "cannot follow templated template file"},
+		{"not yaml before templating", "include:\n{{- if eq .Facts.chassis \"laptop\" }}\n  - laptop.yaml\n{{- end }}\n", "read before templating"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := filepath.Join(t.TempDir(), "build.yaml")
+			os.WriteFile(root, []byte(tt.config), 0644)
+			if _, err := LocalFiles(root); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LocalFiles() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLocalFiles_Templates(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "partials"), 0755)
+	os.WriteFile(filepath.Join(dir, "build.yaml"), []byte("templates: [partials/common.yaml]\ninclude: [child.yaml]\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "child.yaml"), []byte("templates: [partials/common.yaml]\ntasks: []\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "partials", "common.yaml"), []byte(commonPartials), 0644)
+
+	files, err := LocalFiles(filepath.Join(dir, "build.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 || files[2] != filepath.Join(dir, "partials", "common.yaml") {
+		t.Errorf("LocalFiles() = %v", files)
+	}
+}
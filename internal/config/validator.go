@@ -7,6 +7,8 @@ import (
 	"github.com/google/deck"
 	"github.com/mjoliver/glazier-go/internal/actions"
 	"github.com/mjoliver/glazier-go/internal/policy"
+	"github.com/mjoliver/glazier-go/internal/secrets"
+	"github.com/mjoliver/glazier-go/internal/template"
 )
 
 // Validate checks a TaskList for errors without executing actions.
@@ -33,7 +35,24 @@ func Validate(ctx context.Context, tasks TaskList) error {
 				continue
 			}
 
+			if err := secrets.CheckRefs(val); err != nil {
+				deck.Errorf("Task %d [Action %s] Invalid secret reference: %v", i+1, key, err)
+				errorCount++
+				continue
+			}
+
+			if err := template.CheckLate(val); err != nil {
+				deck.Errorf("Task %d [Action %s] Invalid late-bound template: %v", i+1, key, err)
+				errorCount++
+				continue
+			}
+
 			action, err := factory(ctx, val)
+			if err != nil && template.HasLate(val) {
+				// e.g. "${{ .Runtime.delay }}" in a numeric field
+				deck.Warningf("Task %d [Action %s] Not checked until run time: %v", i+1, key, err)
+				continue
+			}
 			if err != nil {
 				deck.Errorf("Task %d [Action %s] Validation Error (Factory): %v", i+1, key, err)
 				errorCount++
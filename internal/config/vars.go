@@ -0,0 +1,159 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Vars are user-defined template variables.
+//
+// Precedence, highest first:
+//  1. -var flags
+//  2. -vars_file
+//  3. vars inherited from the including config
+//  4. the config's own vars: block
+//
+// A config's own vars therefore act as defaults that its includer or the
+// operator can override.
+type Vars map[string]interface{}
+
+// merge returns a copy of v overlaid with each layer in turn.
+func (v Vars) merge(layers ...Vars) Vars {
+	out := Vars{}
+	for k, val := range v {
+		out[k] = val
+	}
+	for _, l := range layers {
+		for k, val := range l {
+			out[k] = val
+		}
+	}
+	return out
+}
+
+// VarConflict records a config's own variable being overridden with a
+// different value.
+type VarConflict struct {
+	File   string
+	Name   string
+	Value  interface{} // the config's own value
+	Winner interface{} // the effective value
+	Source string      // "inherited" or "command line" (-var or -vars_file)
+}
+
+func (c VarConflict) String() string {
+	return fmt.Sprintf("%s: var %q = %v is overridden by %s value %v", c.File, c.Name, c.Value, c.Source, c.Winner)
+}
+
+type varsKey struct{}
+
+// withVars attaches the variables an included config inherits.
+func withVars(ctx context.Context, v Vars) context.Context {
+	return context.WithValue(ctx, varsKey{}, v)
+}
+
+// inheritedVars returns the variables attached with withVars.
+func inheritedVars(ctx context.Context) Vars {
+	v, _ := ctx.Value(varsKey{}).(Vars)
+	return v
+}
+
+// extractVars reads the top-level vars: block from a config before it is
+// templated. The rest of the file may not be valid YAML until rendered, so
+// only the block itself is parsed. Values are literal; templates inside the
+// block are rejected.
+func extractVars(data []byte) (Vars, error) {
+	var doc struct {
+		Vars Vars `yaml:"vars"`
+	}
+	if err := extractBlock(data, "vars", &doc); err != nil {
+		return nil, err
+	}
+	return doc.Vars, nil
+}
+
+// extractBlock decodes a single top-level key of a config that has not been
+// templated yet into out.
+func extractBlock(data []byte, key string, out interface{}) error {
+	var block bytes.Buffer
+	in := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		topLevel := line != "" && line[0] != ' ' && line[0] != '\t' && line[0] != '#'
+		if in && topLevel {
+			break
+		}
+		if !in && strings.HasPrefix(line, key+":") {
+			in = true
+		}
+		if in {
+			block.WriteString(line)
+			block.WriteByte('\n')
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if block.Len() == 0 {
+		return nil
+	}
+	if bytes.Contains(block.Bytes(), []byte("{{")) {
+		return fmt.Errorf("%s: values must be literal, templates are not allowed", key)
+	}
+	if err := yaml.Unmarshal(block.Bytes(), out); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+// LoadVarsFile reads a YAML map of variables.
+func LoadVarsFile(path string) (Vars, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vars file: %w", err)
+	}
+	v := Vars{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("failed to parse vars file %s: %w", path, err)
+	}
+	return v, nil
+}
+
+// ParseVar parses a -var flag value of the form key=value.
+func ParseVar(s string) (string, string, error) {
+	k, v, ok := strings.Cut(s, "=")
+	k = strings.TrimSpace(k)
+	if !ok || k == "" {
+		return "", "", fmt.Errorf("-var %q must be of the form key=value", s)
+	}
+	return k, v, nil
+}
+
+// conflicts reports own variables whose effective value differs. Values are
+// compared as text, since -var values are always strings.
+func conflicts(file string, own, inherited, overrides Vars) []VarConflict {
+	var out []VarConflict
+	names := make([]string, 0, len(own))
+	for k := range own {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		if v, ok := overrides[k]; ok {
+			if fmt.Sprint(v) != fmt.Sprint(own[k]) {
+				out = append(out, VarConflict{file, k, own[k], v, "command line"})
+			}
+		} else if v, ok := inherited[k]; ok && fmt.Sprint(v) != fmt.Sprint(own[k]) {
+			out = append(out, VarConflict{file, k, own[k], v, "inherited"})
+		}
+	}
+	return out
+}
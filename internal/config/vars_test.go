@@ -0,0 +1,128 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mjoliver/glazier-go/internal/template"
+)
+
+func TestExtractVars(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Vars
+		wantErr bool
+	}{
+		{"none", "tasks:\n  - mock.action: {}\n", nil, false},
+		{"block", "# header\nvars:\n  site: nyc\n  # comment\n\n  count: 3\ntasks:\n  - x: '{{.Vars.site}}'\n", Vars{"site": "nyc", "count": 3}, false},
+		{"flow", "vars: {site: nyc}\ntasks: []\n", Vars{"site": "nyc"}, false},
+		{"after tasks", "tasks:\n{{if .Vars.x}}\n  - a: {}\n{{end}}\nvars:\n  x: true\n", Vars{"x": true}, false},
+		{"template in vars", "vars:\n  host: '{{.Hostname}}'\n", nil, true},
+		{"invalid yaml", "vars:\n  - a\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractVars([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractVars() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractVars() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVar(t *testing.T) {
+	if k, v, err := ParseVar("site=nyc=1"); err != nil || k != "site" || v != "nyc=1" {
+		t.Errorf("ParseVar() = %q, %q, %v", k, v, err)
+	}
+	if k, v, err := ParseVar("empty="); err != nil || k != "empty" || v != "" {
+		t.Errorf("ParseVar() = %q, %q, %v", k, v, err)
+	}
+	for _, bad := range []string{"novalue", "=x"} {
+		if _, _, err := ParseVar(bad); err == nil {
+			t.Errorf("ParseVar(%q) expected error", bad)
+		}
+	}
+}
+
+func TestLoadVarsFile(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "vars.yaml")
+	os.WriteFile(p, []byte("site: nyc\nring: 2\n"), 0644)
+	v, err := LoadVarsFile(p)
+	if err != nil || v["site"] != "nyc" || v["ring"] != 2 {
+		t.Errorf("LoadVarsFile() = %v, %v", v, err)
+	}
+
+	empty := filepath.Join(dir, "empty.yaml")
+	os.WriteFile(empty, nil, 0644)
+	if v, err := LoadVarsFile(empty); err != nil || v == nil {
+		t.Errorf("LoadVarsFile(empty) = %v, %v, want an empty map", v, err)
+	}
+}
+
+func TestFetcher_VarsPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"root.yaml": `
+vars:
+  site: nyc
+  ring: 1
+include:
+  - child.yaml
+tasks:
+  - mock.action: {id: "root-{{.Vars.site}}-{{.Vars.ring}}"}
+`,
+		"child.yaml": `
+vars:
+  site: default
+  ring: 0
+  owner: it
+tasks:
+  - mock.action: {id: "child-{{.Vars.site}}-{{.Vars.ring}}-{{.Vars.owner}}"}
+`,
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+
+	info := &template.BuildInfo{Vars: Vars{"ring": "3"}} // as set by -var ring=3
+	f := NewFetcher(info)
+	tasks, err := NewRunner(f).LoadConfig(context.Background(), filepath.Join(dir, "root.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	// child's own vars are overridden by root (site) and the command line (ring)
+	want := []string{"child-nyc-3-it", "root-nyc-3"}
+	for i, task := range tasks {
+		if id := task["mock.action"].(map[string]interface{})["id"]; id != want[i] {
+			t.Errorf("task %d id = %v, want %s", i, id, want[i])
+		}
+	}
+
+	got := f.VarConflicts()
+	if len(got) != 3 {
+		t.Fatalf("VarConflicts() = %v, want 3", got)
+	}
+	wantConflicts := []struct{ file, name, source string }{
+		{"child.yaml", "ring", "command line"},
+		{"child.yaml", "site", "inherited"},
+		{"root.yaml", "ring", "command line"},
+	}
+	for i, w := range wantConflicts {
+		c := got[i]
+		if filepath.Base(c.File) != w.file || c.Name != w.name || c.Source != w.source {
+			t.Errorf("conflict %d = %s, want %s %s from %s", i, c, w.file, w.name, w.source)
+		}
+	}
+	if info.Vars["site"] != nil {
+		t.Error("Fetch() modified the shared BuildInfo")
+	}
+}
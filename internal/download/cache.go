@@ -0,0 +1,193 @@
+package download
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/deck"
+)
+
+// hashAlgorithms maps checksum algorithm names to constructors.
+var hashAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+}
+
+// NewHash returns a hash for the named algorithm.
+func NewHash(algo string) (hash.Hash, error) {
+	fn, ok := hashAlgorithms[strings.ToLower(algo)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported hash algorithm %q", algo)
+	}
+	return fn(), nil
+}
+
+// FileMatches reports whether the file at path exists and has the given digest.
+func FileMatches(path, algo, sum string) bool {
+	h, err := NewHash(algo)
+	if err != nil {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return false
+	}
+	return strings.EqualFold(hex.EncodeToString(h.Sum(nil)), sum)
+}
+
+// Cache is a content-addressed store of verified downloads, laid out as
+// <Dir>/<algo>/<hex digest>.
+type Cache struct {
+	Dir     string
+	MaxSize int64 // bytes; 0 means unlimited
+	Link    bool  // hard-link hits into place instead of copying
+
+	mu sync.Mutex
+}
+
+var defaultCache *Cache
+
+// SetCache installs the cache used by file.download. nil disables caching.
+func SetCache(c *Cache) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	defaultCache = c
+}
+
+// DefaultCache returns the cache set with SetCache, or nil.
+func DefaultCache() *Cache {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return defaultCache
+}
+
+func (c *Cache) path(algo, sum string) string {
+	return filepath.Join(c.Dir, strings.ToLower(algo), strings.ToLower(sum))
+}
+
+// Fetch places the cached file for algo:sum at dst. It returns false if the
+// cache has no valid entry; corrupt entries are removed.
+func (c *Cache) Fetch(algo, sum, dst string) (bool, error) {
+	src := c.path(algo, sum)
+	if _, err := os.Stat(src); err != nil {
+		return false, nil
+	}
+	if !FileMatches(src, algo, sum) {
+		deck.Warningf("download cache: removing corrupt entry %s", src)
+		os.Remove(src)
+		return false, nil
+	}
+
+	now := time.Now()
+	os.Chtimes(src, now, now) // mark as recently used for eviction
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return false, err
+	}
+	os.Remove(dst)
+	if c.Link {
+		if err := os.Link(src, dst); err == nil {
+			return true, nil
+		}
+	}
+	if err := copyTo(src, dst); err != nil {
+		return false, fmt.Errorf("download cache: %w", err)
+	}
+	return true, nil
+}
+
+// Add stores a verified file under algo:sum and evicts old entries if the
+// cache exceeds MaxSize.
+func (c *Cache) Add(algo, sum, src string) error {
+	dst := c.path(algo, sum)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return nil
+	}
+	// Copy to a temp name first so a crash never leaves a truncated entry.
+	tmp := dst + ".tmp"
+	os.Remove(tmp)
+	if linked := c.Link && os.Link(src, tmp) == nil; !linked {
+		if err := copyTo(src, tmp); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return c.evict()
+}
+
+// evict removes least recently used entries until the cache fits MaxSize.
+func (c *Cache) evict() error {
+	if c.MaxSize <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	type entry struct {
+		path string
+		size int64
+		used time.Time
+	}
+	var entries []entry
+	var total int64
+	err := filepath.Walk(c.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return err
+		}
+		entries = append(entries, entry{path, info.Size(), info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].used.Before(entries[j].used) })
+	for _, e := range entries {
+		if total <= c.MaxSize {
+			break
+		}
+		deck.Infof("download cache: evicting %s (%s)", filepath.Base(e.path), FormatBytes(e.size))
+		if err := os.Remove(e.path); err != nil {
+			return err
+		}
+		total -= e.size
+	}
+	return nil
+}
+
+func copyTo(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,110 @@
+package download
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func sum256(b []byte) string {
+	s := sha256.Sum256(b)
+	return hex.EncodeToString(s[:])
+}
+
+func TestFileMatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	os.WriteFile(path, []byte("hello"), 0644)
+
+	if !FileMatches(path, "sha256", sum256([]byte("hello"))) {
+		t.Error("matching file reported as different")
+	}
+	if FileMatches(path, "sha256", sum256([]byte("other"))) {
+		t.Error("different file reported as matching")
+	}
+	if FileMatches(filepath.Join(t.TempDir(), "missing"), "sha256", sum256([]byte("hello"))) {
+		t.Error("missing file reported as matching")
+	}
+	if FileMatches(path, "md4", "00") {
+		t.Error("unknown algorithm reported as matching")
+	}
+}
+
+func TestCache_AddFetch(t *testing.T) {
+	for _, link := range []bool{false, true} {
+		tmp := t.TempDir()
+		c := &Cache{Dir: filepath.Join(tmp, "cache"), Link: link}
+		content := []byte("payload")
+		sum := sum256(content)
+
+		src := filepath.Join(tmp, "src")
+		os.WriteFile(src, content, 0644)
+
+		dst := filepath.Join(tmp, "out", "dst")
+		if hit, _ := c.Fetch("sha256", sum, dst); hit {
+			t.Fatal("Fetch() hit on empty cache")
+		}
+		if err := c.Add("sha256", sum, src); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		hit, err := c.Fetch("sha256", sum, dst)
+		if err != nil || !hit {
+			t.Fatalf("Fetch() = %v, %v; want hit", hit, err)
+		}
+		got, _ := os.ReadFile(dst)
+		if string(got) != "payload" {
+			t.Errorf("link=%v: placed content = %q", link, got)
+		}
+	}
+}
+
+func TestCache_CorruptEntryRemoved(t *testing.T) {
+	tmp := t.TempDir()
+	c := &Cache{Dir: tmp}
+	sum := sum256([]byte("good"))
+	entry := c.path("sha256", sum)
+	os.MkdirAll(filepath.Dir(entry), 0755)
+	os.WriteFile(entry, []byte("tampered"), 0644)
+
+	hit, _ := c.Fetch("sha256", sum, filepath.Join(tmp, "dst"))
+	if hit {
+		t.Error("Fetch() returned a corrupt entry")
+	}
+	if _, err := os.Stat(entry); !os.IsNotExist(err) {
+		t.Error("corrupt entry should be removed")
+	}
+}
+
+func TestCache_Evict(t *testing.T) {
+	tmp := t.TempDir()
+	c := &Cache{Dir: filepath.Join(tmp, "cache"), MaxSize: 25}
+
+	old := time.Now().Add(-time.Hour)
+	var sums []string
+	for i, body := range []string{"aaaaaaaaaa", "bbbbbbbbbb", "cccccccccc"} {
+		src := filepath.Join(tmp, body)
+		os.WriteFile(src, []byte(body), 0644)
+		sum := sum256([]byte(body))
+		sums = append(sums, sum)
+		if err := c.Add("sha256", sum, src); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		// Make insertion order visible to the LRU ordering.
+		when := old.Add(time.Duration(i) * time.Minute)
+		os.Chtimes(c.path("sha256", sum), when, when)
+	}
+	if err := c.evict(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(c.path("sha256", sums[0])); !os.IsNotExist(err) {
+		t.Error("oldest entry should have been evicted")
+	}
+	for _, sum := range sums[1:] {
+		if _, err := os.Stat(c.path("sha256", sum)); err != nil {
+			t.Errorf("entry %s should remain: %v", sum[:8], err)
+		}
+	}
+}
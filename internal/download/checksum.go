@@ -0,0 +1,194 @@
+package download
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mjoliver/glazier-go/internal/httpclient"
+)
+
+func init() {
+	hashAlgorithms["sha1"] = sha1.New
+	hashAlgorithms["sha384"] = sha512.New384
+	hashAlgorithms["sha512"] = sha512.New
+}
+
+// insecureAlgorithms may only be used with an explicit opt-in.
+var insecureAlgorithms = map[string]bool{"sha1": true}
+
+// digestLengths maps hex digest lengths to algorithms for manifests that do
+// not name their algorithm.
+var digestLengths = map[int]string{40: "sha1", 64: "sha256", 96: "sha384", 128: "sha512"}
+
+// Checksum is an expected file digest.
+type Checksum struct {
+	Algo string // "sha256", "sha512", ...
+	Hex  string // lower-case hex digest
+}
+
+func (c Checksum) String() string { return c.Algo + ":" + c.Hex }
+
+// newChecksum validates algo and digest.
+func newChecksum(algo, digest string, allowInsecure bool) (Checksum, error) {
+	algo = strings.ToLower(algo)
+	if _, ok := hashAlgorithms[algo]; !ok {
+		return Checksum{}, fmt.Errorf("unsupported checksum algorithm %q", algo)
+	}
+	if insecureAlgorithms[algo] && !allowInsecure {
+		return Checksum{}, fmt.Errorf("%s is insecure; set insecure_checksum: true to allow it", algo)
+	}
+	digest = strings.ToLower(strings.TrimSpace(digest))
+	if _, err := hex.DecodeString(digest); err != nil || digestLengths[len(digest)] != algo {
+		return Checksum{}, fmt.Errorf("invalid %s digest %q", algo, digest)
+	}
+	return Checksum{Algo: algo, Hex: digest}, nil
+}
+
+// ParseChecksum parses an "algo:hex" value such as "sha512:ab12...".
+func ParseChecksum(s string, allowInsecure bool) (Checksum, error) {
+	algo, digest, ok := strings.Cut(s, ":")
+	if !ok {
+		return Checksum{}, fmt.Errorf("checksum %q must be of the form algo:hex", s)
+	}
+	return newChecksum(algo, digest, allowInsecure)
+}
+
+// LookupChecksum finds the entry for name in a checksum manifest. It accepts
+// GNU coreutils output ("<hex>  <name>" or "<hex> *<name>"), BSD-style
+// lines ("SHA256 (<name>) = <hex>") and single-digest sidecar files such as
+// "file.iso.sha512". name may be a path such as the download URL's; the
+// entry sharing the most trailing path elements with it is used, so
+// "x64/setup.exe" is preferred over "arm64/setup.exe" for
+// "/dl/x64/setup.exe". Two different entries that match equally well are
+// an error rather than a guess.
+func LookupChecksum(manifest []byte, name string, allowInsecure bool) (Checksum, error) {
+	type entry struct{ algo, digest, file string }
+	var entries []entry
+	scanner := bufio.NewScanner(bytes.NewReader(manifest))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		algo, digest, file := parseManifestLine(line)
+		if digest == "" {
+			continue
+		}
+		entries = append(entries, entry{algo, digest, file})
+	}
+	if err := scanner.Err(); err != nil {
+		return Checksum{}, err
+	}
+
+	pick := func(e entry) (Checksum, error) {
+		if e.algo == "" {
+			e.algo = digestLengths[len(e.digest)]
+		}
+		return newChecksum(e.algo, e.digest, allowInsecure)
+	}
+
+	// A sidecar with one bare digest applies to the file it accompanies.
+	if len(entries) == 1 && entries[0].file == "" {
+		return pick(entries[0])
+	}
+	var best []entry
+	bestScore := 0
+	for _, e := range entries {
+		score := commonSuffix(pathElems(e.file), pathElems(name))
+		switch {
+		case score == 0 || score < bestScore:
+		case score > bestScore:
+			best, bestScore = []entry{e}, score
+		default:
+			best = append(best, e)
+		}
+	}
+	if len(best) == 0 {
+		return Checksum{}, fmt.Errorf("no checksum for %q in manifest", name)
+	}
+	for _, e := range best[1:] {
+		if !strings.EqualFold(e.digest, best[0].digest) {
+			files := make([]string, len(best))
+			for i, e := range best {
+				files[i] = e.file
+			}
+			return Checksum{}, fmt.Errorf("checksum for %q is ambiguous in manifest: %s", name, strings.Join(files, ", "))
+		}
+	}
+	return pick(best[0])
+}
+
+// pathElems splits a manifest or URL path into its elements, ignoring "."
+// and empty elements.
+func pathElems(p string) []string {
+	var elems []string
+	for _, e := range strings.Split(strings.ReplaceAll(p, `\`, "/"), "/") {
+		if e != "" && e != "." {
+			elems = append(elems, e)
+		}
+	}
+	return elems
+}
+
+// commonSuffix counts the trailing elements a and b share.
+func commonSuffix(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
+
+// parseManifestLine returns the algorithm (if named), digest and file name.
+func parseManifestLine(line string) (algo, digest, file string) {
+	// BSD: "SHA256 (file.iso) = abcd..."
+	if open := strings.Index(line, " ("); open > 0 {
+		if close := strings.LastIndex(line, ") = "); close > open {
+			return strings.ToLower(line[:open]), line[close+4:], line[open+2 : close]
+		}
+	}
+	// GNU: "abcd...  file.iso" or "abcd... *file.iso"; sidecar: "abcd..."
+	fields := strings.SplitN(line, " ", 2)
+	if _, err := hex.DecodeString(fields[0]); err != nil {
+		return "", "", ""
+	}
+	if len(fields) == 1 {
+		return "", fields[0], ""
+	}
+	file = strings.TrimLeft(fields[1], " ")
+	file = strings.TrimPrefix(file, "*")
+	return "", fields[0], file
+}
+
+// maxManifestSize bounds the size of checksum manifests and signatures.
+const maxManifestSize = 4 << 20
+
+// FetchSmall downloads a small document such as a checksum manifest or a
+// detached signature into memory.
+func FetchSmall(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, httpclient.RedactError(err)
+	}
+	resp, err := httpclient.Do(ctx, client, req, httpclient.DefaultRetryPolicy)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
+	if err != nil {
+		return nil, httpclient.RedactError(err)
+	}
+	if len(data) > maxManifestSize {
+		return nil, fmt.Errorf("%s exceeds %s", httpclient.RedactURL(url), FormatBytes(maxManifestSize))
+	}
+	return data, nil
+}
@@ -0,0 +1,91 @@
+package download
+
+import (
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseChecksum(t *testing.T) {
+	sha512Hex := strings.Repeat("ab", 64)
+	tests := []struct {
+		name          string
+		in            string
+		allowInsecure bool
+		want          Checksum
+		wantErr       bool
+	}{
+		{"sha256", "sha256:" + strings.Repeat("AB", 32), false, Checksum{"sha256", strings.Repeat("ab", 32)}, false},
+		{"sha512", "SHA512:" + sha512Hex, false, Checksum{"sha512", sha512Hex}, false},
+		{"sha384", "sha384:" + strings.Repeat("01", 48), false, Checksum{"sha384", strings.Repeat("01", 48)}, false},
+		{"sha1 rejected", "sha1:" + strings.Repeat("01", 20), false, Checksum{}, true},
+		{"sha1 allowed", "sha1:" + strings.Repeat("01", 20), true, Checksum{"sha1", strings.Repeat("01", 20)}, false},
+		{"md5 unsupported", "md5:" + strings.Repeat("01", 16), true, Checksum{}, true},
+		{"wrong length", "sha512:" + strings.Repeat("ab", 32), false, Checksum{}, true},
+		{"not hex", "sha256:" + strings.Repeat("zz", 32), false, Checksum{}, true},
+		{"no algorithm", strings.Repeat("ab", 32), false, Checksum{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseChecksum(tt.in, tt.allowInsecure)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseChecksum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseChecksum() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupChecksum(t *testing.T) {
+	a, b := strings.Repeat("aa", 32), strings.Repeat("bb", 32)
+	long := strings.Repeat("cc", 64)
+	tests := []struct {
+		name     string
+		manifest string
+		file     string
+		want     Checksum
+		wantErr  bool
+	}{
+		{"gnu text", a + "  setup.exe\n" + b + "  other.exe\n", "other.exe", Checksum{"sha256", b}, false},
+		{"gnu binary", a + " *setup.exe\n", "setup.exe", Checksum{"sha256", a}, false},
+		{"subdirectory", a + "  ./x64/setup.exe\n" + b + "  ./arm64/setup.exe\n", "/dl/arm64/setup.exe", Checksum{"sha256", b}, false},
+		{"ambiguous base name", a + "  ./x64/setup.exe\n" + b + "  ./arm64/setup.exe\n", "/dl/setup.exe", Checksum{}, true},
+		{"same digest twice", a + "  x64/setup.exe\n" + a + "  setup.exe\n", "/dl/arm64/setup.exe", Checksum{"sha256", a}, false},
+		{"base name fallback", a + "  x64/setup.exe\n" + b + "  x64/other.exe\n", "/dl/setup.exe", Checksum{"sha256", a}, false},
+		{"bsd", "# comment\nSHA512 (setup.exe) = " + long + "\n", "setup.exe", Checksum{"sha512", long}, false},
+		{"sidecar", long + "\n", "setup.exe", Checksum{"sha512", long}, false},
+		{"missing entry", a + "  setup.exe\n", "driver.cab", Checksum{}, true},
+		{"sha1 rejected", strings.Repeat("dd", 20) + "  setup.exe\n", "setup.exe", Checksum{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LookupChecksum([]byte(tt.manifest), tt.file, false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LookupChecksum() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("LookupChecksum() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileMatches_Algorithms(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	os.WriteFile(path, []byte("hello"), 0644)
+
+	s512 := sha512.Sum512([]byte("hello"))
+	s1 := sha1.Sum([]byte("hello"))
+	if !FileMatches(path, "sha512", hex.EncodeToString(s512[:])) {
+		t.Error("sha512 digest did not match")
+	}
+	if !FileMatches(path, "sha1", hex.EncodeToString(s1[:])) {
+		t.Error("sha1 digest did not match")
+	}
+}
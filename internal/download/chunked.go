@@ -0,0 +1,306 @@
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/deck"
+	"github.com/mjoliver/glazier-go/internal/httpclient"
+)
+
+// minChunkSize keeps small files on a single stream.
+const minChunkSize = 1 << 20
+
+// chunk is one byte range of a chunked download. End is inclusive.
+type chunk struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	Done  int64 `json:"done"` // bytes written from Start
+}
+
+func (c *chunk) remaining() int64 { return c.End - c.Start + 1 - c.Done }
+
+var errContentChanged = errors.New("content changed on server during chunked download")
+
+// attemptChunked downloads the file as parallel Range requests written into
+// a preallocated .partial file. Per-chunk progress is kept in the partial
+// metadata so an interrupted transfer resumes each chunk where it stopped.
+// Servers without range support fall back to a single stream.
+func (r *Request) attemptChunked(ctx context.Context) error {
+	partial := PartialPath(r.Dst)
+	meta := r.loadMeta()
+	if _, err := os.Stat(partial); err != nil || meta == nil || meta.Chunks == nil {
+		var err error
+		if meta, err = r.planChunks(ctx); err != nil {
+			return err
+		}
+		if meta == nil {
+			return r.attempt(ctx)
+		}
+	}
+
+	f, err := os.OpenFile(partial, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var done atomic.Int64
+	for _, c := range meta.Chunks {
+		done.Add(c.Done)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex // guards meta.Chunks and firstErr
+		firstErr error
+	)
+	for i := range meta.Chunks {
+		if meta.Chunks[i].remaining() <= 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(c *chunk) {
+			defer wg.Done()
+			err := r.fetchChunk(ctx, f, meta, c, &mu, &done)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}(&meta.Chunks[i])
+	}
+
+	stop := make(chan struct{})
+	reported := make(chan struct{})
+	go func() {
+		defer close(reported)
+		pw := &progressWriter{done: done.Load(), total: meta.Total, report: r.Progress, last: time.Now()}
+		pw.lastDone = pw.done
+		ticker := time.NewTicker(r.ProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				pw.done = done.Load()
+				pw.flush()
+				mu.Lock()
+				r.writeMeta(meta)
+				mu.Unlock()
+			case <-stop:
+				pw.done = done.Load()
+				pw.flush()
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(stop)
+	<-reported
+
+	if errors.Is(firstErr, errContentChanged) {
+		f.Close()
+		r.discard()
+		return firstErr
+	}
+	if err := r.writeMeta(meta); err != nil {
+		return err
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	return r.hashFile(f)
+}
+
+// planChunks probes the server with a one-byte Range request. It returns nil
+// meta when the file should be fetched with a single stream instead.
+func (r *Request) planChunks(ctx context.Context) (*partialMeta, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", r.URL, nil)
+	if err != nil {
+		return nil, &httpclient.PermanentError{Err: httpclient.RedactError(err)}
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := httpclient.Do(ctx, r.Client, req, httpclient.RetryPolicy{Attempts: 1})
+	if err != nil {
+		return nil, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	total := parseContentRangeTotal(resp.Header.Get("Content-Range"))
+	etag, lastMod := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	switch {
+	case resp.StatusCode != http.StatusPartialContent || total < 0:
+		deck.Infof("download: %s does not support ranges, using a single stream", httpclient.RedactURL(r.URL))
+		return nil, nil
+	case etag == "" && lastMod == "":
+		deck.Infof("download: %s has no ETag or Last-Modified, using a single stream", httpclient.RedactURL(r.URL))
+		return nil, nil
+	case total < int64(r.Chunks)*minChunkSize:
+		return nil, nil
+	}
+
+	meta := &partialMeta{URL: r.URL, ETag: etag, LastModified: lastMod, Total: total}
+	size := total / int64(r.Chunks)
+	for i := 0; i < r.Chunks; i++ {
+		c := chunk{Start: int64(i) * size, End: int64(i+1)*size - 1}
+		if i == r.Chunks-1 {
+			c.End = total - 1
+		}
+		meta.Chunks = append(meta.Chunks, c)
+	}
+
+	f, err := os.Create(PartialPath(r.Dst))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := f.Truncate(total); err != nil {
+		return nil, err
+	}
+	deck.Infof("download: fetching %s in %d chunks", FormatBytes(total), r.Chunks)
+	return meta, r.writeMeta(meta)
+}
+
+// fetchChunk downloads the rest of c and writes it at its offset in f.
+func (r *Request) fetchChunk(parent context.Context, f *os.File, meta *partialMeta, c *chunk, mu *sync.Mutex, done *atomic.Int64) error {
+	release, err := acquire(parent)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	idle := time.AfterFunc(r.IdleTimeout, cancel)
+	defer idle.Stop()
+
+	mu.Lock()
+	offset := c.Start + c.Done
+	mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", r.URL, nil)
+	if err != nil {
+		return &httpclient.PermanentError{Err: httpclient.RedactError(err)}
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, c.End))
+	if meta.ETag != "" {
+		req.Header.Set("If-Range", meta.ETag)
+	} else {
+		req.Header.Set("If-Range", meta.LastModified)
+	}
+
+	resp, err := httpclient.Do(ctx, r.Client, req, httpclient.RetryPolicy{Attempts: 1})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return errContentChanged
+	}
+	if cr := resp.Header.Get("Content-Range"); parseContentRangeStart(cr) != offset {
+		return fmt.Errorf("chunk from byte %d: server sent range %q: %w", offset, cr, errContentChanged)
+	}
+
+	body := &limitedReader{ctx: ctx, r: io.LimitReader(resp.Body, c.End-offset+1), limiters: r.limiters()}
+	buf := make([]byte, 32<<10)
+	for {
+		n, rerr := body.Read(buf)
+		if n > 0 {
+			if _, err := f.WriteAt(buf[:n], offset); err != nil {
+				return err
+			}
+			offset += int64(n)
+			done.Add(int64(n))
+			mu.Lock()
+			c.Done += int64(n)
+			mu.Unlock()
+			idle.Reset(r.IdleTimeout)
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			if ctx.Err() != nil && parent.Err() == nil {
+				return fmt.Errorf("no data received for %v", r.IdleTimeout)
+			}
+			return httpclient.RedactError(rerr)
+		}
+	}
+	if offset != c.End+1 {
+		return fmt.Errorf("short chunk: got bytes up to %d, want %d", offset-1, c.End)
+	}
+	return nil
+}
+
+// hashFile feeds the completed file to the hashers. Chunks arrive out of
+// order, so chunked downloads hash in one sequential pass at the end.
+func (r *Request) hashFile(f *os.File) error {
+	if len(r.Hashers) == 0 {
+		return nil
+	}
+	writers := make([]io.Writer, len(r.Hashers))
+	for i, h := range r.Hashers {
+		h.Reset()
+		writers[i] = h
+	}
+	_, err := io.Copy(io.MultiWriter(writers...), io.NewSectionReader(f, 0, 1<<62))
+	return err
+}
+
+func (r *Request) writeMeta(m *partialMeta) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(metaPath(r.Dst), data, 0644)
+}
+
+// parseContentRangeStart extracts the first byte position from a header
+// such as "bytes 4000-9999/10000". It returns -1 if there is none.
+func parseContentRangeStart(v string) int64 {
+	rest, ok := strings.CutPrefix(v, "bytes ")
+	if !ok {
+		return -1
+	}
+	i := strings.IndexByte(rest, '-')
+	if i < 0 {
+		return -1
+	}
+	n, err := strconv.ParseInt(rest[:i], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// parseContentRangeTotal extracts the complete length from a header such as
+// "bytes 0-0/12345". It returns -1 if the length is unknown.
+func parseContentRangeTotal(v string) int64 {
+	i := strings.LastIndexByte(v, '/')
+	if i < 0 {
+		return -1
+	}
+	n, err := strconv.ParseInt(v[i+1:], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
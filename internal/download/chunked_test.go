@@ -0,0 +1,239 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGet_Chunked(t *testing.T) {
+	content := payload(4 << 20)
+	var mu sync.Mutex
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	h := sha256.New()
+	err := Get(context.Background(), Request{URL: server.URL, Dst: dst, Retry: testPolicy, Chunks: 4, Hashers: []hash.Hash{h}})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	got, _ := os.ReadFile(dst)
+	if !bytes.Equal(got, content) {
+		t.Fatal("chunked content mismatch")
+	}
+	want := sha256.Sum256(content)
+	if hex.EncodeToString(h.Sum(nil)) != hex.EncodeToString(want[:]) {
+		t.Error("hash mismatch for chunked download")
+	}
+	// one probe plus four chunks
+	if len(ranges) != 5 {
+		t.Errorf("got %d requests, want 5: %q", len(ranges), ranges)
+	}
+}
+
+func TestGet_ChunkedResume(t *testing.T) {
+	content := payload(2 << 20)
+	var mu sync.Mutex
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	half := int64(len(content) / 2)
+
+	// First chunk complete, second chunk 1000 bytes in.
+	partial := make([]byte, len(content))
+	copy(partial, content[:half+1000])
+	os.WriteFile(PartialPath(dst), partial, 0644)
+	meta, _ := json.Marshal(partialMeta{
+		URL: server.URL, ETag: `"v1"`, Total: int64(len(content)),
+		Chunks: []chunk{
+			{Start: 0, End: half - 1, Done: half},
+			{Start: half, End: int64(len(content)) - 1, Done: 1000},
+		},
+	})
+	os.WriteFile(metaPath(dst), meta, 0644)
+
+	if err := Get(context.Background(), Request{URL: server.URL, Dst: dst, Retry: testPolicy, Chunks: 2}); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(ranges) != 1 {
+		t.Fatalf("got %d requests, want only the unfinished chunk: %q", len(ranges), ranges)
+	}
+	if want := "bytes=1049576-2097151"; ranges[0] != want {
+		t.Errorf("Range = %q, want %q", ranges[0], want)
+	}
+	got, _ := os.ReadFile(dst)
+	if !bytes.Equal(got, content) {
+		t.Error("content mismatch after chunked resume")
+	}
+}
+
+func TestGet_ChunkedFallsBackWithoutRanges(t *testing.T) {
+	content := payload(4 << 20)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write(content) // ignores Range
+	}))
+	defer server.Close()
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	if err := Get(context.Background(), Request{URL: server.URL, Dst: dst, Retry: testPolicy, Chunks: 4}); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	got, _ := os.ReadFile(dst)
+	if !bytes.Equal(got, content) {
+		t.Error("content mismatch on fallback")
+	}
+	if calls != 2 {
+		t.Errorf("got %d requests, want probe + single stream", calls)
+	}
+}
+
+func TestGet_ChunkedContentChanged(t *testing.T) {
+	content := payload(2 << 20)
+	server := contentServer(t, content, `"v2"`, nil)
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	os.WriteFile(PartialPath(dst), make([]byte, len(content)), 0644)
+	meta, _ := json.Marshal(partialMeta{
+		URL: server.URL, ETag: `"v1"`, Total: int64(len(content)),
+		Chunks: []chunk{{Start: 0, End: int64(len(content)) - 1, Done: 10}},
+	})
+	os.WriteFile(metaPath(dst), meta, 0644)
+
+	// The stale plan is discarded and the next attempt starts fresh.
+	if err := Get(context.Background(), Request{URL: server.URL, Dst: dst, Retry: testPolicy, Chunks: 2}); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	got, _ := os.ReadFile(dst)
+	if !bytes.Equal(got, content) {
+		t.Error("content mismatch after server change")
+	}
+}
+
+func TestGet_ChunkedWrongRange(t *testing.T) {
+	// payload repeats, so it would look the same from any chunk offset.
+	content := make([]byte, 2<<20)
+	rand.New(rand.NewSource(1)).Read(content)
+	var mu sync.Mutex
+	misbehaved := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		mu.Lock()
+		rng := r.Header.Get("Range")
+		misbehave := !misbehaved && rng != "" && !strings.HasPrefix(rng, "bytes=0-")
+		if misbehave {
+			misbehaved = true
+		}
+		mu.Unlock()
+		if misbehave {
+			// Answer one later chunk from the start of the file.
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(content)-1, len(content)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(content)
+			return
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	if err := Get(context.Background(), Request{URL: server.URL, Dst: dst, Retry: testPolicy, Chunks: 2}); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	got, _ := os.ReadFile(dst)
+	if !misbehaved || !bytes.Equal(got, content) {
+		t.Error("content mismatch after a chunk was answered with the wrong range")
+	}
+}
+
+func TestGet_MaxConcurrent(t *testing.T) {
+	SetMaxConcurrent(1)
+	defer SetMaxConcurrent(0)
+
+	content := payload(4 << 20)
+	var mu sync.Mutex
+	inflight, peak := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inflight++
+		if inflight > peak {
+			peak = inflight
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inflight--
+			mu.Unlock()
+		}()
+		time.Sleep(10 * time.Millisecond)
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	if err := Get(context.Background(), Request{URL: server.URL, Dst: dst, Retry: testPolicy, Chunks: 4}); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if peak != 1 {
+		t.Errorf("peak concurrent requests = %d, want 1", peak)
+	}
+}
+
+func TestParseContentRangeStart(t *testing.T) {
+	tests := map[string]int64{
+		"bytes 4000-9999/10000": 4000,
+		"bytes 0-0/*":           0,
+		"bytes */10000":         -1,
+		"":                      -1,
+	}
+	for in, want := range tests {
+		if got := parseContentRangeStart(in); got != want {
+			t.Errorf("parseContentRangeStart(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestParseContentRangeTotal(t *testing.T) {
+	tests := map[string]int64{
+		"bytes 0-0/12345": 12345,
+		"bytes 0-0/*":     -1,
+		"":                -1,
+	}
+	for in, want := range tests {
+		if got := parseContentRangeTotal(in); got != want {
+			t.Errorf("parseContentRangeTotal(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
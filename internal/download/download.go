@@ -0,0 +1,365 @@
+// Package download implements resumable HTTP file transfers for file.download.
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/google/deck"
+	"github.com/mjoliver/glazier-go/internal/httpclient"
+)
+
+// Request describes a single file transfer.
+type Request struct {
+	URL    string
+	Dst    string
+	Client *http.Client
+	Retry  httpclient.RetryPolicy
+
+	// Hashers receive every byte of the file, including any part resumed from
+	// a previous attempt, so callers can verify the result without re-reading it.
+	Hashers []hash.Hash
+
+	// Verify is called once the transfer completes, before the file is moved
+	// to Dst. An error discards the downloaded data.
+	Verify func() error
+
+	// Progress, if set, is called every ProgressInterval and once at the end.
+	Progress         func(Progress)
+	ProgressInterval time.Duration
+
+	// IdleTimeout aborts an attempt that receives no data for this long.
+	// The next attempt resumes where it stopped.
+	IdleTimeout time.Duration
+
+	// MaxRate caps this transfer in bytes per second, on top of the global
+	// cap set with SetMaxRate. 0 means no per-request cap.
+	MaxRate int64
+
+	// Chunks splits the transfer into this many parallel Range requests when
+	// the server supports them. Values below 2 use a single stream.
+	Chunks int
+
+	limiter *Limiter
+}
+
+// Progress reports the state of a transfer.
+type Progress struct {
+	Done  int64   // bytes written so far
+	Total int64   // expected size, -1 if unknown
+	Rate  float64 // bytes per second since the previous report
+}
+
+// partialMeta is stored next to the .partial file so a later attempt can
+// check that the server still serves the same content before resuming.
+type partialMeta struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Total        int64  `json:"total"`
+
+	// Chunks records per-chunk progress for chunked downloads.
+	Chunks []chunk `json:"chunks,omitempty"`
+}
+
+// PartialPath returns where in-progress data for dst is kept.
+func PartialPath(dst string) string { return dst + ".partial" }
+
+func metaPath(dst string) string { return dst + ".partial.json" }
+
+// Get downloads r.URL to r.Dst. Data is written to a .partial file first and
+// renamed into place only after Verify succeeds. If a .partial file from an
+// earlier attempt exists and the server's validators still match, the
+// transfer resumes with a Range request instead of starting over.
+func Get(ctx context.Context, r Request) error {
+	if r.Client == nil {
+		r.Client = httpclient.New(0)
+	}
+	if r.ProgressInterval == 0 {
+		r.ProgressInterval = 5 * time.Second
+	}
+	if r.IdleTimeout == 0 {
+		r.IdleTimeout = time.Minute
+	}
+	r.limiter = NewLimiter(r.MaxRate)
+	if err := os.MkdirAll(filepath.Dir(r.Dst), 0755); err != nil {
+		return err
+	}
+
+	attempts := r.Retry.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if r.Chunks > 1 {
+			lastErr = r.attemptChunked(ctx)
+		} else {
+			lastErr = r.attempt(ctx)
+		}
+		if lastErr == nil {
+			return r.commit()
+		}
+		if ctx.Err() != nil || httpclient.IsPermanent(lastErr) || errors.Is(lastErr, errVerify) {
+			break
+		}
+		if attempt < attempts {
+			delay := r.Retry.Backoff(attempt, httpclient.RetryAfter(lastErr))
+			deck.Warningf("download %s interrupted: %v (resuming in %v)", httpclient.RedactURL(r.URL), lastErr, delay)
+			if err := httpclient.Sleep(ctx, delay); err != nil {
+				return err
+			}
+		}
+	}
+	return lastErr
+}
+
+var errVerify = errors.New("verification failed")
+
+// attempt performs one request, resuming from any existing partial data.
+func (r *Request) attempt(parent context.Context) error {
+	release, err := acquire(parent)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	idle := time.AfterFunc(r.IdleTimeout, cancel)
+	defer idle.Stop()
+
+	partial := PartialPath(r.Dst)
+	meta := r.loadMeta()
+	offset := int64(0)
+	if meta != nil && meta.Chunks == nil {
+		if info, err := os.Stat(partial); err == nil {
+			offset = info.Size()
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", r.URL, nil)
+	if err != nil {
+		return &httpclient.PermanentError{Err: httpclient.RedactError(err)}
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if meta.ETag != "" {
+			req.Header.Set("If-Range", meta.ETag)
+		} else {
+			req.Header.Set("If-Range", meta.LastModified)
+		}
+	}
+
+	// Status-level retries are handled by Get so that every retry resumes.
+	resp, err := httpclient.Do(ctx, r.Client, req, httpclient.RetryPolicy{Attempts: 1})
+	if err != nil {
+		var serr *httpclient.StatusError
+		if errors.As(err, &serr) && serr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			r.discard()
+			return fmt.Errorf("server rejected resume range, restarting: %w", err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_WRONLY | os.O_CREATE
+	total := resp.ContentLength
+	start := int64(-1)
+	if resp.StatusCode == http.StatusPartialContent {
+		start = parseContentRangeStart(resp.Header.Get("Content-Range"))
+	}
+	switch {
+	case offset > 0 && start == offset:
+		deck.Infof("download: resuming %s at byte %d", r.Dst, offset)
+		flags |= os.O_APPEND
+		if total >= 0 {
+			total += offset
+		}
+	case resp.StatusCode == http.StatusPartialContent && start != 0:
+		// A range that neither resumes nor starts over cannot be used.
+		r.discard()
+		return fmt.Errorf("server sent range %q for a request from byte %d, restarting", resp.Header.Get("Content-Range"), offset)
+	default:
+		if offset > 0 {
+			deck.Infof("download: server content changed or range unsupported, restarting %s", r.Dst)
+		}
+		if start == 0 {
+			total = parseContentRangeTotal(resp.Header.Get("Content-Range"))
+		}
+		offset = 0
+		flags |= os.O_TRUNC
+	}
+
+	if err := r.saveMeta(resp, total); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(partial, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	for _, h := range r.Hashers {
+		h.Reset()
+	}
+	if offset > 0 {
+		if err := r.hashExisting(partial, offset); err != nil {
+			return err
+		}
+	}
+
+	w := io.Writer(out)
+	if len(r.Hashers) > 0 {
+		writers := []io.Writer{out}
+		for _, h := range r.Hashers {
+			writers = append(writers, h)
+		}
+		w = io.MultiWriter(writers...)
+	}
+
+	pw := &progressWriter{w: w, done: offset, total: total, interval: r.ProgressInterval, report: r.Progress, last: time.Now(), lastDone: offset}
+	pw.onWrite = func() { idle.Reset(r.IdleTimeout) }
+	body := &limitedReader{ctx: ctx, r: resp.Body, limiters: r.limiters()}
+	if _, err := io.Copy(pw, body); err != nil {
+		if ctx.Err() != nil && parent.Err() == nil {
+			return fmt.Errorf("no data received for %v", r.IdleTimeout)
+		}
+		return httpclient.RedactError(err)
+	}
+	pw.flush()
+	if total >= 0 && pw.done != total {
+		return fmt.Errorf("short download: got %d of %d bytes", pw.done, total)
+	}
+	return out.Close()
+}
+
+// hashExisting feeds the already-downloaded prefix of the partial file to the hashers.
+func (r *Request) hashExisting(partial string, n int64) error {
+	f, err := os.Open(partial)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	writers := make([]io.Writer, len(r.Hashers))
+	for i, h := range r.Hashers {
+		writers[i] = h
+	}
+	_, err = io.CopyN(io.MultiWriter(writers...), f, n)
+	return err
+}
+
+// commit verifies the completed partial file and moves it to Dst.
+func (r *Request) commit() error {
+	if r.Verify != nil {
+		if err := r.Verify(); err != nil {
+			r.discard() // Security: never leave unverified data behind
+			return fmt.Errorf("%w: %v", errVerify, err)
+		}
+	}
+	os.Remove(metaPath(r.Dst))
+	if err := os.Rename(PartialPath(r.Dst), r.Dst); err != nil {
+		// Windows cannot rename over an existing file.
+		os.Remove(r.Dst)
+		if err := os.Rename(PartialPath(r.Dst), r.Dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// discard removes partial data and its metadata.
+func (r *Request) discard() {
+	os.Remove(PartialPath(r.Dst))
+	os.Remove(metaPath(r.Dst))
+}
+
+// loadMeta returns the stored validators if a resume is possible.
+func (r *Request) loadMeta() *partialMeta {
+	data, err := os.ReadFile(metaPath(r.Dst))
+	if err != nil {
+		return nil
+	}
+	var m partialMeta
+	if err := json.Unmarshal(data, &m); err != nil || m.URL != r.URL {
+		return nil
+	}
+	if m.ETag == "" && m.LastModified == "" {
+		return nil // nothing to validate against, so never trust the partial
+	}
+	return &m
+}
+
+func (r *Request) saveMeta(resp *http.Response, total int64) error {
+	return r.writeMeta(&partialMeta{
+		URL:          r.URL,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Total:        total,
+	})
+}
+
+// progressWriter counts bytes and reports progress at a fixed interval.
+type progressWriter struct {
+	w        io.Writer
+	done     int64
+	total    int64
+	interval time.Duration
+	report   func(Progress)
+	onWrite  func()
+	last     time.Time
+	lastDone int64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.done += int64(n)
+	if p.onWrite != nil {
+		p.onWrite()
+	}
+	if p.report != nil && time.Since(p.last) >= p.interval {
+		p.flush()
+	}
+	return n, err
+}
+
+func (p *progressWriter) flush() {
+	if p.report == nil {
+		return
+	}
+	now := time.Now()
+	rate := 0.0
+	if elapsed := now.Sub(p.last).Seconds(); elapsed > 0 {
+		rate = float64(p.done-p.lastDone) / elapsed
+	}
+	p.report(Progress{Done: p.done, Total: p.total, Rate: rate})
+	p.last, p.lastDone = now, p.done
+}
+
+// FormatBytes renders a byte count for log messages.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < 0 {
+		return "unknown"
+	}
+	if n < unit {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,291 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mjoliver/glazier-go/internal/httpclient"
+)
+
+var testPolicy = httpclient.RetryPolicy{Attempts: 3, BaseDelay: 10 * time.Millisecond}
+
+// payload returns deterministic test content of n bytes.
+func payload(n int) []byte {
+	return bytes.Repeat([]byte("0123456789abcdef"), n/16+1)[:n]
+}
+
+// contentServer serves content with an ETag and Range support.
+func contentServer(t *testing.T, content []byte, etag string, ranges *[]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ranges != nil {
+			*ranges = append(*ranges, r.Header.Get("Range"))
+		}
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func writePartial(t *testing.T, dst, url, etag string, data []byte) {
+	t.Helper()
+	os.WriteFile(PartialPath(dst), data, 0644)
+	os.WriteFile(metaPath(dst), []byte(fmt.Sprintf(`{"url":%q,"etag":%q}`, url, etag)), 0644)
+}
+
+func TestGet_Simple(t *testing.T) {
+	content := payload(4096)
+	server := contentServer(t, content, `"v1"`, nil)
+	dst := filepath.Join(t.TempDir(), "sub", "file.bin")
+
+	h := sha256.New()
+	err := Get(context.Background(), Request{URL: server.URL, Dst: dst, Retry: testPolicy, Hashers: []hash.Hash{h}})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	got, _ := os.ReadFile(dst)
+	if !bytes.Equal(got, content) {
+		t.Error("downloaded content mismatch")
+	}
+	want := sha256.Sum256(content)
+	if hex.EncodeToString(h.Sum(nil)) != hex.EncodeToString(want[:]) {
+		t.Error("streamed hash mismatch")
+	}
+	if _, err := os.Stat(PartialPath(dst)); !os.IsNotExist(err) {
+		t.Error(".partial file should be gone after success")
+	}
+	if _, err := os.Stat(metaPath(dst)); !os.IsNotExist(err) {
+		t.Error("partial metadata should be gone after success")
+	}
+}
+
+func TestGet_Resume(t *testing.T) {
+	content := payload(10000)
+	var ranges []string
+	server := contentServer(t, content, `"v1"`, &ranges)
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	writePartial(t, dst, server.URL, `"v1"`, content[:4000])
+
+	h := sha256.New()
+	err := Get(context.Background(), Request{URL: server.URL, Dst: dst, Retry: testPolicy, Hashers: []hash.Hash{h}})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if len(ranges) != 1 || ranges[0] != "bytes=4000-" {
+		t.Errorf("Range headers = %q, want [bytes=4000-]", ranges)
+	}
+	got, _ := os.ReadFile(dst)
+	if !bytes.Equal(got, content) {
+		t.Error("resumed content mismatch")
+	}
+	want := sha256.Sum256(content)
+	if hex.EncodeToString(h.Sum(nil)) != hex.EncodeToString(want[:]) {
+		t.Error("hash should cover the resumed prefix too")
+	}
+}
+
+func TestGet_ResumeValidatorChanged(t *testing.T) {
+	content := payload(10000)
+	server := contentServer(t, content, `"v2"`, nil)
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	writePartial(t, dst, server.URL, `"v1"`, []byte(strings.Repeat("x", 4000)))
+
+	if err := Get(context.Background(), Request{URL: server.URL, Dst: dst, Retry: testPolicy}); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	got, _ := os.ReadFile(dst)
+	if !bytes.Equal(got, content) {
+		t.Error("stale partial data should have been replaced")
+	}
+}
+
+func TestGet_ResumeWrongOffset(t *testing.T) {
+	content := payload(10000)
+	tests := []struct {
+		name         string
+		contentRange string // for a request from byte 4000
+		body         []byte
+		wantRanges   int
+	}{
+		{"from start", "bytes 0-9999/10000", content, 1},
+		{"elsewhere", "bytes 2000-9999/10000", content[2000:], 2},
+		{"missing", "", content[4000:], 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ranges []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ranges = append(ranges, r.Header.Get("Range"))
+				w.Header().Set("ETag", `"v1"`)
+				if r.Header.Get("Range") == "" {
+					w.Write(content)
+					return
+				}
+				if tt.contentRange != "" {
+					w.Header().Set("Content-Range", tt.contentRange)
+				}
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write(tt.body)
+			}))
+			defer server.Close()
+			dst := filepath.Join(t.TempDir(), "file.bin")
+			writePartial(t, dst, server.URL, `"v1"`, content[:4000])
+
+			if err := Get(context.Background(), Request{URL: server.URL, Dst: dst, Retry: testPolicy}); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			got, _ := os.ReadFile(dst)
+			if !bytes.Equal(got, content) {
+				t.Errorf("content of %d bytes does not match, want the partial data replaced", len(got))
+			}
+			if len(ranges) != tt.wantRanges {
+				t.Errorf("Range headers = %q, want %d requests", ranges, tt.wantRanges)
+			}
+		})
+	}
+}
+
+func TestGet_ResumeWithoutValidators(t *testing.T) {
+	content := payload(1000)
+	var ranges []string
+	server := contentServer(t, content, "", &ranges)
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	os.WriteFile(PartialPath(dst), []byte("garbage"), 0644)
+	os.WriteFile(metaPath(dst), []byte(fmt.Sprintf(`{"url":%q}`, server.URL)), 0644)
+
+	if err := Get(context.Background(), Request{URL: server.URL, Dst: dst, Retry: testPolicy}); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if ranges[0] != "" {
+		t.Errorf("should not resume without validators, sent Range %q", ranges[0])
+	}
+	got, _ := os.ReadFile(dst)
+	if !bytes.Equal(got, content) {
+		t.Error("content mismatch")
+	}
+}
+
+func TestGet_InterruptedTransferResumes(t *testing.T) {
+	content := payload(64 << 10)
+	var ranges []string
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", `"v1"`)
+		if calls == 1 {
+			// Send half the body, then drop the connection.
+			w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	dst := filepath.Join(t.TempDir(), "file.bin")
+	if err := Get(context.Background(), Request{URL: server.URL, Dst: dst, Retry: testPolicy}); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("server calls = %d, want 2", calls)
+	}
+	if ranges[1] == "" {
+		t.Error("second attempt should resume with a Range request")
+	}
+	got, _ := os.ReadFile(dst)
+	if !bytes.Equal(got, content) {
+		t.Error("content mismatch after resume")
+	}
+}
+
+func TestGet_VerifyFailureDiscards(t *testing.T) {
+	server := contentServer(t, payload(100), `"v1"`, nil)
+	dst := filepath.Join(t.TempDir(), "file.bin")
+
+	err := Get(context.Background(), Request{
+		URL:    server.URL,
+		Dst:    dst,
+		Retry:  testPolicy,
+		Verify: func() error { return fmt.Errorf("bad hash") },
+	})
+	if err == nil {
+		t.Fatal("Get() expected error")
+	}
+	for _, p := range []string{dst, PartialPath(dst), metaPath(dst)} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s should not exist after failed verification", p)
+		}
+	}
+}
+
+func TestGet_NotFound(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	err := Get(context.Background(), Request{URL: server.URL, Dst: filepath.Join(t.TempDir(), "f"), Retry: testPolicy})
+	if !httpclient.IsPermanent(err) {
+		t.Errorf("Get() error = %v, want permanent", err)
+	}
+	if calls != 1 {
+		t.Errorf("404 retried: %d calls", calls)
+	}
+}
+
+func TestGet_Progress(t *testing.T) {
+	content := payload(32 << 10)
+	server := contentServer(t, content, `"v1"`, nil)
+
+	var reports []Progress
+	err := Get(context.Background(), Request{
+		URL:              server.URL,
+		Dst:              filepath.Join(t.TempDir(), "file.bin"),
+		Retry:            testPolicy,
+		Progress:         func(p Progress) { reports = append(reports, p) },
+		ProgressInterval: time.Nanosecond,
+	})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(reports) == 0 {
+		t.Fatal("no progress reported")
+	}
+	last := reports[len(reports)-1]
+	if last.Done != int64(len(content)) || last.Total != int64(len(content)) {
+		t.Errorf("final progress = %+v, want done=total=%d", last, len(content))
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		-1:      "unknown",
+		512:     "512 B",
+		2048:    "2.0 KiB",
+		5 << 30: "5.0 GiB",
+	}
+	for in, want := range tests {
+		if got := FormatBytes(in); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
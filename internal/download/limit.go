@@ -0,0 +1,170 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mjoliver/glazier-go/internal/httpclient"
+)
+
+// Limiter is a token bucket that caps throughput in bytes per second.
+// A nil *Limiter imposes no limit.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a limiter for bytesPerSec, or nil if bytesPerSec <= 0.
+func NewLimiter(bytesPerSec int64) *Limiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	burst := float64(bytesPerSec) / 4 // smooth out at 250ms granularity
+	if burst < 32<<10 {
+		burst = 32 << 10
+	}
+	return &Limiter{rate: float64(bytesPerSec), burst: burst, tokens: burst, last: time.Now()}
+}
+
+// WaitN blocks until n bytes may be transferred or ctx is done.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if wait > 0 {
+		return httpclient.Sleep(ctx, wait)
+	}
+	return nil
+}
+
+// limitedReader throttles reads through one or more limiters.
+type limitedReader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiters []*Limiter
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > 32<<10 {
+		p = p[:32<<10]
+	}
+	n, err := lr.r.Read(p)
+	for _, l := range lr.limiters {
+		if werr := l.WaitN(lr.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+var (
+	globalMu      sync.RWMutex
+	globalLimiter *Limiter
+	slots         chan struct{}
+)
+
+// SetMaxRate caps the combined throughput of all downloads. 0 disables the cap.
+func SetMaxRate(bytesPerSec int64) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	globalLimiter = NewLimiter(bytesPerSec)
+}
+
+// SetMaxConcurrent caps the number of simultaneous transfers; every chunk of
+// a chunked download counts as one. 0 disables the cap.
+func SetMaxConcurrent(n int) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	if n <= 0 {
+		slots = nil
+		return
+	}
+	slots = make(chan struct{}, n)
+}
+
+// acquire waits for a transfer slot and returns a function that releases it.
+func acquire(ctx context.Context) (func(), error) {
+	globalMu.RLock()
+	s := slots
+	globalMu.RUnlock()
+	if s == nil {
+		return func() {}, nil
+	}
+	select {
+	case s <- struct{}{}:
+		return func() { <-s }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// limiters returns the global limiter and the per-request one, if set.
+func (r *Request) limiters() []*Limiter {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	var ls []*Limiter
+	if globalLimiter != nil {
+		ls = append(ls, globalLimiter)
+	}
+	if r.limiter != nil {
+		ls = append(ls, r.limiter)
+	}
+	return ls
+}
+
+// ParseRate parses a byte rate such as "500K", "10MB/s" or "1.5G" (per
+// second). A bare number is bytes per second; "" and "0" mean no limit.
+func ParseRate(rate string) (int64, error) {
+	return ParseSize(strings.TrimSuffix(strings.TrimSpace(strings.ToUpper(rate)), "/S"))
+}
+
+// ParseSize parses a byte count such as "500K", "10MB" or "1.5GiB" using
+// binary multiples. A bare number is bytes; "" means zero.
+func ParseSize(size string) (int64, error) {
+	s := strings.TrimSpace(strings.ToUpper(size))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+	if s == "" {
+		return 0, nil
+	}
+	mult := 1.0
+	switch s[len(s)-1] {
+	case 'K':
+		mult = 1 << 10
+	case 'M':
+		mult = 1 << 20
+	case 'G':
+		mult = 1 << 30
+	case 'T':
+		mult = 1 << 40
+	}
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return int64(v * mult), nil
+}
@@ -0,0 +1,92 @@
+package download
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"1000", 1000, false},
+		{"500K", 500 << 10, false},
+		{"10MB", 10 << 20, false},
+		{"10mib/s", 10 << 20, false},
+		{"1.5G", 3 << 29, false},
+		{"fast", 0, true},
+		{"-5M", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRate(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLimiter_WaitN(t *testing.T) {
+	l := NewLimiter(100 << 10) // 100 KiB/s, 32 KiB burst
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := l.WaitN(ctx, 32<<10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 128 KiB with a 32 KiB burst needs ~0.96s of refill.
+	if elapsed := time.Since(start); elapsed < 800*time.Millisecond {
+		t.Errorf("limiter allowed 128 KiB in %v at 100 KiB/s", elapsed)
+	}
+}
+
+func TestLimiter_Nil(t *testing.T) {
+	var l *Limiter
+	if err := l.WaitN(context.Background(), 1<<30); err != nil {
+		t.Errorf("nil limiter should not block, got %v", err)
+	}
+	if NewLimiter(0) != nil {
+		t.Error("NewLimiter(0) should be nil")
+	}
+}
+
+func TestLimiter_ContextCanceled(t *testing.T) {
+	l := NewLimiter(1024)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	l.WaitN(ctx, 32<<10) // drain burst
+	if err := l.WaitN(ctx, 1<<20); err == nil {
+		t.Error("WaitN() should return the context error")
+	}
+}
+
+func TestGet_MaxRate(t *testing.T) {
+	content := payload(256 << 10)
+	server := contentServer(t, content, `"v1"`, nil)
+
+	start := time.Now()
+	err := Get(context.Background(), Request{
+		URL:     server.URL,
+		Dst:     filepath.Join(t.TempDir(), "file.bin"),
+		Retry:   testPolicy,
+		MaxRate: 512 << 10,
+	})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	// 256 KiB at 512 KiB/s with a 128 KiB burst takes about 250ms.
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("rate limit not applied: 256 KiB took %v", elapsed)
+	}
+}
@@ -0,0 +1,90 @@
+package download
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseKeyRing(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(nil)
+	enc := base64.StdEncoding.EncodeToString(pub)
+
+	keys, err := ParseKeyRing([]byte("# release key\n" + enc + " # vendor\n\n"))
+	if err != nil {
+		t.Fatalf("ParseKeyRing() error = %v", err)
+	}
+	if len(keys) != 1 || !keys[0].Equal(pub) {
+		t.Errorf("ParseKeyRing() = %v, want one key", keys)
+	}
+
+	if _, err := ParseKeyRing([]byte("not-a-key\n")); err == nil {
+		t.Error("ParseKeyRing() accepted an invalid key")
+	}
+}
+
+func TestKeyRing_Verify(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	other, _, _ := ed25519.GenerateKey(nil)
+	msg := []byte("manifest")
+	sig := ed25519.Sign(priv, msg)
+
+	tests := []struct {
+		name    string
+		keys    KeyRing
+		msg     []byte
+		wantErr bool
+	}{
+		{"trusted key", KeyRing{other, pub}, msg, false},
+		{"untrusted key", KeyRing{other}, msg, true},
+		{"tampered message", KeyRing{pub}, []byte("manifest!"), true},
+		{"no keys", nil, msg, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.keys.Verify(tt.msg, sig)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSignature(t *testing.T) {
+	raw := make([]byte, ed25519.SignatureSize)
+	raw[0] = 1
+	for _, in := range [][]byte{raw, []byte(base64.StdEncoding.EncodeToString(raw) + "\n")} {
+		sig, err := ParseSignature(in)
+		if err != nil || sig[0] != 1 {
+			t.Errorf("ParseSignature(%q) = %v, %v", in, sig, err)
+		}
+	}
+	if _, err := ParseSignature([]byte("short")); err == nil {
+		t.Error("ParseSignature() accepted a short signature")
+	}
+}
+
+func TestKeyRing_VerifyFile(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	content := []byte("artifact")
+	path := filepath.Join(t.TempDir(), "artifact")
+	os.WriteFile(path, content, 0644)
+	digest := sha512.Sum512(content)
+	phSig, _ := priv.Sign(nil, digest[:], &ed25519.Options{Hash: crypto.SHA512})
+
+	keys := KeyRing{pub}
+	if err := keys.VerifyFile(path, digest[:], phSig); err != nil {
+		t.Errorf("prehashed signature rejected: %v", err)
+	}
+	if err := keys.VerifyFile(path, digest[:], ed25519.Sign(priv, content)); err != nil {
+		t.Errorf("plain signature rejected: %v", err)
+	}
+	other := sha512.Sum512([]byte("other"))
+	if err := keys.VerifyFile(path, other[:], phSig); err == nil {
+		t.Error("prehashed signature accepted for a different digest")
+	}
+}
@@ -0,0 +1,213 @@
+// Package facts collects hardware and system information for templates and
+// policies. Facts are a flat map with snake_case keys:
+//
+//	manufacturer, model, serial, bios_version, chassis  string
+//	uefi, secure_boot, tpm, virtual                     bool
+//	tpm_version, cpu, arch, hypervisor                  string
+//	cpu_cores, cpu_threads, ram_gb                      int
+//	ram_bytes                                           int64
+//	disks  []map{name, model, size_bytes}
+//	nics   []map{name, mac, ips}
+//	macs, ips                                           []string
+//
+// Facts that cannot be read are omitted.
+package facts
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Facts maps fact names to values.
+type Facts map[string]interface{}
+
+// String returns the fact as a string, or "" if it is not set.
+func (f Facts) String(key string) string {
+	v, ok := f[key]
+	if !ok || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// Int returns the fact as an integer. ok is false if it is not set or not
+// a number.
+func (f Facts) Int(key string) (n int64, ok bool) {
+	switch v := f[key].(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), true
+	case float64:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+// Bool returns the fact as a bool. ok is false if it is not set or not a
+// bool.
+func (f Facts) Bool(key string) (b, ok bool) {
+	b, ok = f[key].(bool)
+	return b, ok
+}
+
+// Provider collects facts about the current machine.
+type Provider interface {
+	Collect(ctx context.Context) (Facts, error)
+}
+
+var (
+	mu       sync.Mutex
+	provider Provider = platformProvider{}
+	cached   Facts
+)
+
+// SetProvider replaces the provider used by Current and clears the cache.
+func SetProvider(p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	provider = p
+	cached = nil
+}
+
+// Current returns the facts for this machine, collecting them on first use.
+func Current(ctx context.Context) (Facts, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+	f, err := provider.Collect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("collecting facts: %w", err)
+	}
+	cached = f
+	return f, nil
+}
+
+// FakeProvider returns fixed facts. It is used in tests and with -facts_file
+// to evaluate configs as if on another machine.
+type FakeProvider struct {
+	Facts Facts
+}
+
+func (p FakeProvider) Collect(ctx context.Context) (Facts, error) {
+	return p.Facts, nil
+}
+
+// LoadFakeProvider reads facts from a YAML map.
+func LoadFakeProvider(path string) (FakeProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FakeProvider{}, fmt.Errorf("failed to read facts file: %w", err)
+	}
+	f := Facts{}
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return FakeProvider{}, fmt.Errorf("failed to parse facts file %s: %w", path, err)
+	}
+	return FakeProvider{Facts: f}, nil
+}
+
+// addNetwork records NICs, MACs and IP addresses from the standard library,
+// which works the same on every platform.
+func addNetwork(f Facts) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return
+	}
+	var nics []interface{}
+	var macs, ips []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) == 0 {
+			continue
+		}
+		mac := strings.ToUpper(iface.HardwareAddr.String())
+		var nicIPs []string
+		if addrs, err := iface.Addrs(); err == nil {
+			for _, a := range addrs {
+				if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLinkLocalUnicast() {
+					nicIPs = append(nicIPs, ipnet.IP.String())
+				}
+			}
+		}
+		nics = append(nics, map[string]interface{}{"name": iface.Name, "mac": mac, "ips": nicIPs})
+		macs = append(macs, mac)
+		ips = append(ips, nicIPs...)
+	}
+	sort.Strings(ips)
+	f["nics"] = nics
+	f["macs"] = macs
+	f["ips"] = ips
+}
+
+// chassisName maps an SMBIOS chassis type code to a policy-friendly name.
+func chassisName(code int) string {
+	switch code {
+	case 1:
+		return "other"
+	case 2:
+		return "unknown"
+	case 3, 4, 5, 6, 7:
+		return "desktop"
+	case 8, 9, 10, 11, 12, 14, 18, 21:
+		return "laptop"
+	case 13:
+		return "allinone"
+	case 15, 16:
+		return "server"
+	case 17:
+		return "docking"
+	case 30, 31, 32:
+		return "tablet"
+	default:
+		return fmt.Sprintf("type_%d", code)
+	}
+}
+
+// hypervisors maps substrings of the reported manufacturer or model to
+// hypervisor names. Hyper-V guests report "Microsoft Corporation" with a
+// "Virtual Machine" model, so Surface devices are not mistaken for VMs.
+var hypervisors = []struct{ match, name string }{
+	{"virtual machine", "hyperv"},
+	{"vmware", "vmware"},
+	{"virtualbox", "virtualbox"},
+	{"innotek", "virtualbox"},
+	{"qemu", "kvm"},
+	{"kvm", "kvm"},
+	{"xen", "xen"},
+	{"parallels", "parallels"},
+	{"amazon ec2", "aws"},
+	{"google compute engine", "gce"},
+}
+
+// addVirtual records whether the machine is a VM, based on the manufacturer
+// and model facts already collected.
+func addVirtual(f Facts) {
+	manufacturer, model := f.String("manufacturer"), f.String("model")
+	if manufacturer == "" && model == "" {
+		return
+	}
+	s := strings.ToLower(manufacturer + " " + model)
+	f["virtual"] = false
+	for _, h := range hypervisors {
+		if strings.Contains(s, h.match) {
+			f["virtual"] = true
+			f["hypervisor"] = h.name
+			return
+		}
+	}
+}
+
+// gibibytes rounds a byte count to whole GiB.
+func gibibytes(n int64) int {
+	return int((n + 1<<29) >> 30)
+}
@@ -0,0 +1,176 @@
+//go:build !windows
+
+package facts
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// sysRoot is prefixed to /sys and /proc paths so tests can use a fake tree.
+var sysRoot = "/"
+
+// platformProvider reads facts from /sys and /proc.
+type platformProvider struct{}
+
+func (platformProvider) Collect(ctx context.Context) (Facts, error) {
+	f := Facts{}
+	dmi := map[string]string{
+		"manufacturer": "sys_vendor",
+		"model":        "product_name",
+		"serial":       "product_serial",
+		"bios_version": "bios_version",
+	}
+	for key, file := range dmi {
+		if v := readSys("sys/class/dmi/id", file); v != "" {
+			f[key] = v
+		}
+	}
+	if code, err := strconv.Atoi(readSys("sys/class/dmi/id", "chassis_type")); err == nil {
+		f["chassis"] = chassisName(code)
+	}
+
+	addFirmware(f)
+	// Without sysfs the TPM state is unknown rather than absent.
+	if exists("sys/class") {
+		f["tpm"] = exists("sys/class/tpm/tpm0")
+		if v := readSys("sys/class/tpm/tpm0", "tpm_version_major"); v != "" {
+			f["tpm_version"] = v + ".0"
+		}
+	}
+
+	f["arch"] = runtime.GOARCH
+	addCPU(f)
+	addMemory(f)
+	addDisks(f)
+	addNetwork(f)
+	addVirtual(f)
+	return f, nil
+}
+
+// addFirmware sets uefi and secure_boot. Both are left unset without
+// sysfs, and secure_boot is left unset on UEFI machines whose efivars are
+// not mounted.
+func addFirmware(f Facts) {
+	if !exists("sys/firmware") {
+		return
+	}
+	uefi := exists("sys/firmware/efi")
+	f["uefi"] = uefi
+	if !uefi {
+		f["secure_boot"] = false
+		return
+	}
+	if matches, _ := filepath.Glob(filepath.Join(sysRoot, "sys/firmware/efi/efivars/SecureBoot-*")); len(matches) > 0 {
+		// efivars data is a 4-byte attribute header followed by the value.
+		if data, err := os.ReadFile(matches[0]); err == nil && len(data) >= 5 {
+			f["secure_boot"] = data[4] == 1
+		}
+	} else if exists("sys/firmware/efi/efivars") {
+		f["secure_boot"] = false
+	}
+}
+
+func exists(rel string) bool {
+	_, err := os.Stat(filepath.Join(sysRoot, rel))
+	return err == nil
+}
+
+func readSys(dir, name string) string {
+	data, err := os.ReadFile(filepath.Join(sysRoot, dir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func addCPU(f Facts) {
+	file, err := os.Open(filepath.Join(sysRoot, "proc/cpuinfo"))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	cores := map[string]bool{}
+	threads := 0
+	var physical, core string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		switch k {
+		case "processor":
+			threads++
+		case "model name":
+			if _, ok := f["cpu"]; !ok {
+				f["cpu"] = v
+			}
+		case "physical id":
+			physical = v
+		case "core id":
+			core = v
+			cores[physical+"/"+core] = true
+		}
+	}
+	if threads == 0 {
+		threads = runtime.NumCPU()
+	}
+	f["cpu_threads"] = threads
+	if len(cores) > 0 {
+		f["cpu_cores"] = len(cores)
+	} else {
+		f["cpu_cores"] = threads
+	}
+}
+
+func addMemory(f Facts) {
+	file, err := os.Open(filepath.Join(sysRoot, "proc/meminfo"))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err == nil {
+				f["ram_bytes"] = kb * 1024
+				f["ram_gb"] = gibibytes(kb * 1024)
+			}
+			return
+		}
+	}
+}
+
+func addDisks(f Facts) {
+	entries, err := os.ReadDir(filepath.Join(sysRoot, "sys/block"))
+	if err != nil {
+		return
+	}
+	var disks []interface{}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, "loop") || strings.HasPrefix(name, "ram") || strings.HasPrefix(name, "zram") {
+			continue
+		}
+		dir := filepath.Join("sys/block", name)
+		sectors, err := strconv.ParseInt(readSys(dir, "size"), 10, 64)
+		if err != nil || sectors == 0 {
+			continue
+		}
+		disks = append(disks, map[string]interface{}{
+			"name":       name,
+			"model":      readSys(dir, "device/model"),
+			"size_bytes": sectors * 512, // /sys/block sizes are always in 512-byte units
+		})
+	}
+	f["disks"] = disks
+}
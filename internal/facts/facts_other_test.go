@@ -0,0 +1,90 @@
+//go:build !windows
+
+package facts
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlatformProvider_SysRoot(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"sys/class/dmi/id/sys_vendor":                  "Dell Inc.\n",
+		"sys/class/dmi/id/product_name":                "Latitude 7440\n",
+		"sys/class/dmi/id/product_serial":              "ABC123\n",
+		"sys/class/dmi/id/chassis_type":                "10\n",
+		"sys/firmware/efi/efivars/SecureBoot-8be4df61": "\x06\x00\x00\x00\x01",
+		"sys/class/tpm/tpm0/tpm_version_major":         "2\n",
+		"proc/cpuinfo":                                 "processor\t: 0\nmodel name\t: Test CPU\nphysical id\t: 0\ncore id\t: 0\n\nprocessor\t: 1\nmodel name\t: Test CPU\nphysical id\t: 0\ncore id\t: 0\n",
+		"proc/meminfo":                                 "MemTotal:       16777216 kB\nMemFree: 1 kB\n",
+		"sys/block/nvme0n1/size":                       "1000215216\n",
+		"sys/block/nvme0n1/device/model":               "Test SSD\n",
+		"sys/block/loop0/size":                         "100\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte(content), 0644)
+	}
+	orig := sysRoot
+	sysRoot = root
+	defer func() { sysRoot = orig }()
+
+	f, err := platformProvider{}.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	want := map[string]interface{}{
+		"manufacturer": "Dell Inc.",
+		"model":        "Latitude 7440",
+		"serial":       "ABC123",
+		"chassis":      "laptop",
+		"uefi":         true,
+		"secure_boot":  true,
+		"tpm":          true,
+		"tpm_version":  "2.0",
+		"cpu":          "Test CPU",
+		"cpu_cores":    1,
+		"cpu_threads":  2,
+		"ram_gb":       16,
+		"virtual":      false,
+	}
+	for k, v := range want {
+		if f[k] != v {
+			t.Errorf("fact %s = %v, want %v", k, f[k], v)
+		}
+	}
+	disks, _ := f["disks"].([]interface{})
+	if len(disks) != 1 {
+		t.Fatalf("disks = %v, want only nvme0n1", f["disks"])
+	}
+	if d := disks[0].(map[string]interface{}); d["name"] != "nvme0n1" || d["model"] != "Test SSD" || d["size_bytes"] != int64(1000215216*512) {
+		t.Errorf("disk = %v", d)
+	}
+}
+
+func TestPlatformProvider_UnknownSecurityState(t *testing.T) {
+	orig := sysRoot
+	defer func() { sysRoot = orig }()
+
+	// No sysfs at all: nothing is known.
+	sysRoot = t.TempDir()
+	f, _ := platformProvider{}.Collect(context.Background())
+	for _, k := range []string{"uefi", "secure_boot", "tpm"} {
+		if v, ok := f[k]; ok {
+			t.Errorf("fact %s = %v without sysfs, want unset", k, v)
+		}
+	}
+
+	// UEFI without efivars: Secure Boot unknown; sysfs without a TPM: no TPM.
+	for _, dir := range []string{"sys/firmware/efi", "sys/class"} {
+		os.MkdirAll(filepath.Join(sysRoot, dir), 0755)
+	}
+	f, _ = platformProvider{}.Collect(context.Background())
+	if _, ok := f["secure_boot"]; ok || f["uefi"] != true || f["tpm"] != false {
+		t.Errorf("uefi = %v, secure_boot = %v, tpm = %v; want true, unset, false", f["uefi"], f["secure_boot"], f["tpm"])
+	}
+}
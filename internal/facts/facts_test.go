@@ -0,0 +1,123 @@
+package facts
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) Collect(ctx context.Context) (Facts, error) {
+	p.calls++
+	return Facts{"serial": "ABC123"}, nil
+}
+
+func TestCurrent_Caches(t *testing.T) {
+	p := &countingProvider{}
+	SetProvider(p)
+	defer SetProvider(platformProvider{})
+
+	for i := 0; i < 3; i++ {
+		f, err := Current(context.Background())
+		if err != nil || f.String("serial") != "ABC123" {
+			t.Fatalf("Current() = %v, %v", f, err)
+		}
+	}
+	if p.calls != 1 {
+		t.Errorf("Collect() called %d times, want 1", p.calls)
+	}
+
+	SetProvider(p)
+	Current(context.Background())
+	if p.calls != 2 {
+		t.Errorf("SetProvider() did not clear the cache")
+	}
+}
+
+func TestLoadFakeProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "facts.yaml")
+	os.WriteFile(path, []byte("model: Latitude 7440\nram_gb: 16\ntpm: true\nmacs: [\"AA:BB:CC:DD:EE:FF\"]\n"), 0644)
+
+	p, err := LoadFakeProvider(path)
+	if err != nil {
+		t.Fatalf("LoadFakeProvider() error = %v", err)
+	}
+	f, _ := p.Collect(context.Background())
+	if f.String("model") != "Latitude 7440" || f["ram_gb"] != 16 || f["tpm"] != true {
+		t.Errorf("LoadFakeProvider() facts = %v", f)
+	}
+	if f.String("missing") != "" {
+		t.Errorf("String(missing) = %q, want empty", f.String("missing"))
+	}
+
+	if _, err := LoadFakeProvider(filepath.Join(t.TempDir(), "none.yaml")); err == nil {
+		t.Error("LoadFakeProvider() expected error for a missing file")
+	}
+}
+
+func TestChassisName(t *testing.T) {
+	tests := map[int]string{3: "desktop", 10: "laptop", 13: "allinone", 17: "docking", 31: "tablet", 99: "type_99"}
+	for code, want := range tests {
+		if got := chassisName(code); got != want {
+			t.Errorf("chassisName(%d) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestGibibytes(t *testing.T) {
+	if got := gibibytes(16 << 30); got != 16 {
+		t.Errorf("gibibytes(16GiB) = %d", got)
+	}
+	if got := gibibytes(16<<30 - 100<<20); got != 16 { // firmware-reserved memory rounds up
+		t.Errorf("gibibytes(~16GiB) = %d", got)
+	}
+}
+
+func TestAddVirtual(t *testing.T) {
+	tests := []struct {
+		manufacturer, model string
+		wantVirtual         interface{}
+		wantHypervisor      interface{}
+	}{
+		{"Microsoft Corporation", "Virtual Machine", true, "hyperv"},
+		{"Microsoft Corporation", "Surface Pro 9", false, nil},
+		{"VMware, Inc.", "VMware7,1", true, "vmware"},
+		{"innotek GmbH", "VirtualBox", true, "virtualbox"},
+		{"QEMU", "Standard PC (Q35 + ICH9, 2009)", true, "kvm"},
+		{"Dell Inc.", "Latitude 7440", false, nil},
+		{"", "", nil, nil},
+	}
+	for _, tt := range tests {
+		f := Facts{}
+		if tt.manufacturer != "" {
+			f["manufacturer"], f["model"] = tt.manufacturer, tt.model
+		}
+		addVirtual(f)
+		if f["virtual"] != tt.wantVirtual || f["hypervisor"] != tt.wantHypervisor {
+			t.Errorf("addVirtual(%q, %q) = %v, %v, want %v, %v", tt.manufacturer, tt.model, f["virtual"], f["hypervisor"], tt.wantVirtual, tt.wantHypervisor)
+		}
+	}
+}
+
+func TestFacts_IntBool(t *testing.T) {
+	f := Facts{"a": 16, "b": int64(1 << 40), "c": "x", "d": true}
+	if n, ok := f.Int("a"); !ok || n != 16 {
+		t.Errorf("Int(a) = %d, %v", n, ok)
+	}
+	if n, ok := f.Int("b"); !ok || n != 1<<40 {
+		t.Errorf("Int(b) = %d, %v", n, ok)
+	}
+	if _, ok := f.Int("c"); ok {
+		t.Error("Int(c) ok for a string")
+	}
+	if b, ok := f.Bool("d"); !ok || !b {
+		t.Errorf("Bool(d) = %v, %v", b, ok)
+	}
+	if _, ok := f.Bool("missing"); ok {
+		t.Error("Bool(missing) ok")
+	}
+}
@@ -0,0 +1,171 @@
+//go:build windows
+
+package facts
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/StackExchange/wmi"
+	"github.com/google/deck"
+	"golang.org/x/sys/windows/registry"
+)
+
+// WMI types
+type win32ComputerSystem struct {
+	Manufacturer        string
+	Model               string
+	TotalPhysicalMemory uint64
+}
+
+type win32BIOS struct {
+	SerialNumber      string
+	SMBIOSBIOSVersion string
+}
+
+type win32SystemEnclosure struct {
+	ChassisTypes []int32
+}
+
+type win32Processor struct {
+	Name                      string
+	NumberOfCores             uint32
+	NumberOfLogicalProcessors uint32
+}
+
+type win32DiskDrive struct {
+	DeviceID string
+	Model    string
+	Size     uint64
+}
+
+type win32Tpm struct {
+	SpecVersion string
+}
+
+// platformProvider reads facts from WMI and the registry.
+type platformProvider struct{}
+
+func (platformProvider) Collect(ctx context.Context) (Facts, error) {
+	f := Facts{}
+
+	var cs []win32ComputerSystem
+	if query("SELECT Manufacturer, Model, TotalPhysicalMemory FROM Win32_ComputerSystem", &cs) && len(cs) > 0 {
+		f["manufacturer"] = strings.TrimSpace(cs[0].Manufacturer)
+		f["model"] = strings.TrimSpace(cs[0].Model)
+		f["ram_bytes"] = int64(cs[0].TotalPhysicalMemory)
+		f["ram_gb"] = gibibytes(int64(cs[0].TotalPhysicalMemory))
+	}
+
+	var bios []win32BIOS
+	if query("SELECT SerialNumber, SMBIOSBIOSVersion FROM Win32_BIOS", &bios) && len(bios) > 0 {
+		f["serial"] = strings.TrimSpace(bios[0].SerialNumber)
+		f["bios_version"] = strings.TrimSpace(bios[0].SMBIOSBIOSVersion)
+	}
+
+	var enc []win32SystemEnclosure
+	if query("SELECT ChassisTypes FROM Win32_SystemEnclosure", &enc) && len(enc) > 0 && len(enc[0].ChassisTypes) > 0 {
+		f["chassis"] = chassisName(int(enc[0].ChassisTypes[0]))
+	}
+
+	var cpus []win32Processor
+	if query("SELECT Name, NumberOfCores, NumberOfLogicalProcessors FROM Win32_Processor", &cpus) && len(cpus) > 0 {
+		f["cpu"] = strings.TrimSpace(cpus[0].Name)
+		cores, threads := 0, 0
+		for _, c := range cpus {
+			cores += int(c.NumberOfCores)
+			threads += int(c.NumberOfLogicalProcessors)
+		}
+		f["cpu_cores"] = cores
+		f["cpu_threads"] = threads
+	}
+
+	var drives []win32DiskDrive
+	if query("SELECT DeviceID, Model, Size FROM Win32_DiskDrive", &drives) {
+		var disks []interface{}
+		for _, d := range drives {
+			disks = append(disks, map[string]interface{}{
+				"name":       d.DeviceID,
+				"model":      strings.TrimSpace(d.Model),
+				"size_bytes": int64(d.Size),
+			})
+		}
+		f["disks"] = disks
+	}
+
+	// A failed query (e.g. access denied) leaves tpm unknown rather than false.
+	var tpms []win32Tpm
+	if err := wmi.QueryNamespace("SELECT SpecVersion FROM Win32_Tpm", &tpms, `root\CIMV2\Security\MicrosoftTpm`); err != nil {
+		deck.Warningf("facts: Win32_Tpm: %v", err)
+	} else {
+		f["tpm"] = len(tpms) > 0
+		if len(tpms) > 0 {
+			// SpecVersion is e.g. "2.0, 0, 1.59"
+			f["tpm_version"] = strings.TrimSpace(strings.Split(tpms[0].SpecVersion, ",")[0])
+		}
+	}
+
+	addFirmware(f)
+	f["arch"] = nativeArch()
+	addNetwork(f)
+	addVirtual(f)
+	return f, nil
+}
+
+// nativeArch returns the machine architecture in GOARCH form. A 32-bit
+// process on 64-bit Windows sees PROCESSOR_ARCHITEW6432 set to the real one.
+func nativeArch() string {
+	arch := os.Getenv("PROCESSOR_ARCHITEW6432")
+	if arch == "" {
+		arch = os.Getenv("PROCESSOR_ARCHITECTURE")
+	}
+	switch strings.ToUpper(arch) {
+	case "AMD64":
+		return "amd64"
+	case "ARM64":
+		return "arm64"
+	case "X86":
+		return "386"
+	}
+	return runtime.GOARCH
+}
+
+// query runs a WMI query, logging failures so a single broken class does
+// not hide the remaining facts.
+func query(q string, dst interface{}) bool {
+	if err := wmi.Query(q, dst); err != nil {
+		deck.Warningf("facts: %s: %v", q, err)
+		return false
+	}
+	return true
+}
+
+// addFirmware sets uefi and secure_boot. Values that cannot be read are
+// left unset.
+func addFirmware(f Facts) {
+	if k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control`, registry.QUERY_VALUE); err == nil {
+		// PEFirmwareType: 1 = BIOS, 2 = UEFI
+		if v, _, err := k.GetIntegerValue("PEFirmwareType"); err == nil {
+			f["uefi"] = v == 2
+		}
+		k.Close()
+	}
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control\SecureBoot\State`, registry.QUERY_VALUE)
+	switch {
+	case err == nil:
+		f["uefi"] = true // the key only exists on UEFI systems
+		if v, _, err := k.GetIntegerValue("UEFISecureBootEnabled"); err == nil {
+			f["secure_boot"] = v == 1
+		}
+		k.Close()
+	case errors.Is(err, registry.ErrNotExist):
+		// Without the key the firmware has no Secure Boot, provided the
+		// firmware type could be read at all.
+		if _, ok := f["uefi"]; ok {
+			f["secure_boot"] = false
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package httpclient
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/mjoliver/glazier-go/internal/securefile"
+)
+
+// AuthConfig is the schema of the file passed to -auth_config.
+type AuthConfig struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule attaches credentials to requests whose host matches Host.
+// Credential values are references ("env:NAME", "file:PATH", ...) and are
+// resolved when the rules are loaded, never taken literally from YAML.
+type Rule struct {
+	Host       string            `yaml:"host"`        // "artifacts.example.com" or "*.example.com"
+	PathPrefix string            `yaml:"path_prefix"` // optional, e.g. "/private/"
+	Bearer     string            `yaml:"bearer"`      // reference to a bearer token
+	Username   string            `yaml:"username"`    // literal or reference
+	Password   string            `yaml:"password"`    // reference to a basic auth password
+	Headers    map[string]string `yaml:"headers"`     // header name -> reference
+	Insecure   bool              `yaml:"insecure"`    // allow sending credentials over plain http
+
+	// resolved values, populated by Resolve
+	bearer   string
+	username string
+	password string
+	headers  map[string]string
+}
+
+// SourceFunc resolves the part of a credential reference after the scheme.
+type SourceFunc func(ref string) (string, error)
+
+var sources = map[string]SourceFunc{}
+
+// RegisterSource adds a credential source for references of the form "scheme:ref".
+func RegisterSource(scheme string, fn SourceFunc) {
+	if _, exists := sources[scheme]; exists {
+		panic(fmt.Sprintf("credential source %s already registered", scheme))
+	}
+	sources[scheme] = fn
+}
+
+func init() {
+	RegisterSource("env", func(name string) (string, error) {
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return v, nil
+	})
+	RegisterSource("file", securefile.Read)
+}
+
+// resolveRef looks up a credential reference. Values without a known scheme
+// are rejected so that secrets cannot be written inline.
+func resolveRef(ref string) (string, error) {
+	scheme, rest, ok := strings.Cut(ref, ":")
+	if !ok {
+		return "", fmt.Errorf("credential must be a reference like env:NAME or file:PATH")
+	}
+	fn, ok := sources[scheme]
+	if !ok {
+		return "", fmt.Errorf("unknown credential source %q", scheme)
+	}
+	v, err := fn(rest)
+	if err != nil {
+		return "", fmt.Errorf("%s source: %w", scheme, err)
+	}
+	return v, nil
+}
+
+// Resolve validates the rule and looks up all referenced credential values.
+func (r *Rule) Resolve() error {
+	if r.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if r.Bearer != "" && (r.Username != "" || r.Password != "") {
+		return fmt.Errorf("host %s: bearer and basic auth are mutually exclusive", r.Host)
+	}
+	if (r.Username == "") != (r.Password == "") {
+		return fmt.Errorf("host %s: username and password must be set together", r.Host)
+	}
+
+	var err error
+	if r.Bearer != "" {
+		if r.bearer, err = resolveRef(r.Bearer); err != nil {
+			return fmt.Errorf("host %s bearer: %w", r.Host, err)
+		}
+	}
+	if r.Username != "" {
+		r.username = r.Username
+		if scheme, _, _ := strings.Cut(r.Username, ":"); sources[scheme] != nil {
+			if r.username, err = resolveRef(r.Username); err != nil {
+				return fmt.Errorf("host %s username: %w", r.Host, err)
+			}
+		}
+		if r.password, err = resolveRef(r.Password); err != nil {
+			return fmt.Errorf("host %s password: %w", r.Host, err)
+		}
+	}
+	r.headers = make(map[string]string, len(r.Headers))
+	for name, ref := range r.Headers {
+		if r.headers[name], err = resolveRef(ref); err != nil {
+			return fmt.Errorf("host %s header %s: %w", r.Host, name, err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the rule applies to the request.
+func (r *Rule) matches(req *http.Request) bool {
+	host := strings.ToLower(req.URL.Hostname())
+	pattern := strings.ToLower(r.Host)
+	if ok, _ := path.Match(pattern, host); !ok && pattern != host {
+		return false
+	}
+	if r.PathPrefix != "" && !strings.HasPrefix(req.URL.Path, r.PathPrefix) {
+		return false
+	}
+	return true
+}
+
+// apply sets the rule's credentials on req.
+func (r *Rule) apply(req *http.Request) {
+	switch {
+	case r.bearer != "":
+		req.Header.Set("Authorization", "Bearer "+r.bearer)
+	case r.username != "":
+		token := base64.StdEncoding.EncodeToString([]byte(r.username + ":" + r.password))
+		req.Header.Set("Authorization", "Basic "+token)
+	}
+	for name, v := range r.headers {
+		req.Header.Set(name, v)
+	}
+}
+
+// LoadAuthConfig reads credential rules from a YAML file and resolves them.
+func LoadAuthConfig(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("auth config: %w", err)
+	}
+	var cfg AuthConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("auth config: %w", err)
+	}
+	for i := range cfg.Rules {
+		if err := cfg.Rules[i].Resolve(); err != nil {
+			return nil, fmt.Errorf("auth config rule %d: %w", i+1, err)
+		}
+	}
+	return cfg.Rules, nil
+}
+
+// AuthTransport is an http.RoundTripper that attaches credentials from the
+// first matching rule. Rules are evaluated per request, so credentials are
+// not forwarded when a redirect leaves the matching host.
+type AuthTransport struct {
+	Base  http.RoundTripper
+	Rules []Rule
+}
+
+func (t *AuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	for i := range t.Rules {
+		rule := &t.Rules[i]
+		if !rule.matches(req) {
+			continue
+		}
+		if req.URL.Scheme != "https" && !rule.Insecure {
+			return nil, &PermanentError{Err: fmt.Errorf("refusing to send credentials for %s over %s", rule.Host, req.URL.Scheme)}
+		}
+		req = req.Clone(req.Context())
+		rule.apply(req)
+		break
+	}
+	return base.RoundTrip(req)
+}
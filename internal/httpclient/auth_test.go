@@ -0,0 +1,149 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRule_Resolve(t *testing.T) {
+	t.Setenv("GLAZIER_TEST_TOKEN", "s3cret")
+	tmp := t.TempDir()
+	pwFile := filepath.Join(tmp, "pw")
+	os.WriteFile(pwFile, []byte("filepass\n"), 0600)
+
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{"bearer from env", Rule{Host: "a.com", Bearer: "env:GLAZIER_TEST_TOKEN"}, false},
+		{"basic from file", Rule{Host: "a.com", Username: "bob", Password: "file:" + pwFile}, false},
+		{"header from env", Rule{Host: "a.com", Headers: map[string]string{"X-Key": "env:GLAZIER_TEST_TOKEN"}}, false},
+		{"literal bearer rejected", Rule{Host: "a.com", Bearer: "plaintext"}, true},
+		{"unknown source", Rule{Host: "a.com", Bearer: "vault:x"}, true},
+		{"missing env", Rule{Host: "a.com", Bearer: "env:GLAZIER_TEST_MISSING"}, true},
+		{"missing host", Rule{Bearer: "env:GLAZIER_TEST_TOKEN"}, true},
+		{"bearer and basic", Rule{Host: "a.com", Bearer: "env:GLAZIER_TEST_TOKEN", Username: "u", Password: "env:GLAZIER_TEST_TOKEN"}, true},
+		{"username without password", Rule{Host: "a.com", Username: "u"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Resolve()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && strings.Contains(err.Error(), "s3cret") {
+				t.Errorf("Resolve() error leaks credential: %v", err)
+			}
+		})
+	}
+}
+
+func TestAuthTransport(t *testing.T) {
+	t.Setenv("GLAZIER_TEST_TOKEN", "s3cret")
+
+	var gotAuth, gotKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotKey = r.Header.Get("X-Key")
+	}))
+	defer server.Close()
+
+	rule := Rule{
+		Host:     "127.0.0.1",
+		Bearer:   "env:GLAZIER_TEST_TOKEN",
+		Headers:  map[string]string{"X-Key": "env:GLAZIER_TEST_TOKEN"},
+		Insecure: true,
+	}
+	if err := rule.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Timeout: 5 * time.Second, Transport: &AuthTransport{Rules: []Rule{rule}}}
+
+	resp, err := client.Get(server.URL + "/file")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if gotAuth != "Bearer s3cret" {
+		t.Errorf("Authorization = %q, want %q", gotAuth, "Bearer s3cret")
+	}
+	if gotKey != "s3cret" {
+		t.Errorf("X-Key = %q, want %q", gotKey, "s3cret")
+	}
+}
+
+func TestAuthTransport_NoMatch(t *testing.T) {
+	t.Setenv("GLAZIER_TEST_TOKEN", "s3cret")
+
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	rule := Rule{Host: "*.example.com", Bearer: "env:GLAZIER_TEST_TOKEN", Insecure: true}
+	if err := rule.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &AuthTransport{Rules: []Rule{rule}}}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if gotAuth != "" {
+		t.Errorf("credentials sent to non-matching host: %q", gotAuth)
+	}
+}
+
+func TestAuthTransport_RefusesPlainHTTP(t *testing.T) {
+	t.Setenv("GLAZIER_TEST_TOKEN", "s3cret")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	rule := Rule{Host: "127.0.0.1", Bearer: "env:GLAZIER_TEST_TOKEN"}
+	if err := rule.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &AuthTransport{Rules: []Rule{rule}}}
+
+	if _, err := client.Get(server.URL); err == nil {
+		t.Error("expected error sending credentials over http")
+	}
+}
+
+func TestLoadAuthConfig(t *testing.T) {
+	t.Setenv("GLAZIER_TEST_TOKEN", "s3cret")
+	path := filepath.Join(t.TempDir(), "auth.yaml")
+	os.WriteFile(path, []byte(`
+rules:
+  - host: artifacts.example.com
+    bearer: env:GLAZIER_TEST_TOKEN
+  - host: "*.corp.example.com"
+    path_prefix: /private/
+    username: deploy
+    password: env:GLAZIER_TEST_TOKEN
+`), 0600)
+
+	rules, err := LoadAuthConfig(path)
+	if err != nil {
+		t.Fatalf("LoadAuthConfig() error = %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("got %d rules, want 2", len(rules))
+	}
+	if rules[1].username != "deploy" || rules[1].password != "s3cret" {
+		t.Errorf("basic auth not resolved: %q/%q", rules[1].username, rules[1].password)
+	}
+}
@@ -0,0 +1,31 @@
+// Package httpclient provides the HTTP client shared by the config Fetcher
+// and the file.download action.
+package httpclient
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	mu    sync.RWMutex
+	rules []Rule
+)
+
+// SetRules installs the credential rules used by clients returned from New.
+func SetRules(r []Rule) {
+	mu.Lock()
+	defer mu.Unlock()
+	rules = r
+}
+
+// New returns an http.Client that applies the configured credential rules.
+func New(timeout time.Duration) *http.Client {
+	mu.RLock()
+	defer mu.RUnlock()
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &AuthTransport{Rules: rules},
+	}
+}
@@ -0,0 +1,48 @@
+package httpclient
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// RedactURL strips passwords and query-string values from a URL so it can be
+// logged. Local paths are returned unchanged and unparseable URLs are
+// replaced with a fixed placeholder.
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		if strings.Contains(raw, "://") {
+			return "<invalid url>"
+		}
+		return raw
+	}
+	if u.Host == "" {
+		return raw
+	}
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+	}
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			q[k] = []string{redacted}
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+// RedactError rewrites the URL inside a *url.Error, which net/http includes
+// verbatim in its error messages.
+func RedactError(err error) error {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		uerr.URL = RedactURL(uerr.URL)
+	}
+	return err
+}
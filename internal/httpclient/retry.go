@@ -0,0 +1,168 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/deck"
+)
+
+// RetryPolicy controls how Do retries transient failures.
+type RetryPolicy struct {
+	Attempts      int           // total attempts, including the first
+	BaseDelay     time.Duration // delay before the second attempt; doubles each time
+	MaxDelay      time.Duration // cap on the computed backoff
+	MaxRetryAfter time.Duration // cap on a server-supplied Retry-After
+}
+
+// DefaultRetryPolicy is used by the Fetcher and file.download.
+var DefaultRetryPolicy = RetryPolicy{
+	Attempts:      3,
+	BaseDelay:     1 * time.Second,
+	MaxDelay:      30 * time.Second,
+	MaxRetryAfter: 2 * time.Minute,
+}
+
+// StatusError is returned when a server answers with a non-2xx status.
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Duration // parsed Retry-After header, zero if absent
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("bad status code: %d", e.StatusCode)
+}
+
+// Temporary reports whether the status is worth retrying: 408, 429 and 5xx.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusRequestTimeout ||
+		e.StatusCode == http.StatusTooManyRequests ||
+		e.StatusCode >= 500
+}
+
+// PermanentError wraps a failure that retrying cannot fix.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// IsPermanent reports whether err is an HTTP failure that will not succeed on
+// retry, such as a 404 or a refused credential. Unknown errors are treated as
+// transient.
+func IsPermanent(err error) bool {
+	var perr *PermanentError
+	if errors.As(err, &perr) {
+		return true
+	}
+	var serr *StatusError
+	if errors.As(err, &serr) {
+		return !serr.Temporary()
+	}
+	return false
+}
+
+// RetryAfter returns the server-requested delay carried by err, if any.
+func RetryAfter(err error) time.Duration {
+	var serr *StatusError
+	if errors.As(err, &serr) {
+		return serr.RetryAfter
+	}
+	return 0
+}
+
+// Do sends req, retrying network errors and 408/429/5xx responses according
+// to p. It returns the first 2xx response; the caller must close its body.
+// req must not have a body, since it is cloned for every attempt.
+func Do(ctx context.Context, client *http.Client, req *http.Request, p RetryPolicy) (*http.Response, error) {
+	attempts := p.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		resp, err := client.Do(req.Clone(ctx))
+		if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = RedactError(err)
+		} else {
+			lastErr = &StatusError{
+				StatusCode: resp.StatusCode,
+				RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		if IsPermanent(lastErr) || attempt == attempts {
+			break
+		}
+
+		delay := p.Backoff(attempt, RetryAfter(lastErr))
+		deck.Warningf("HTTP %s attempt %d/%d failed: %v (retrying in %v)", RedactURL(req.URL.String()), attempt, attempts, lastErr, delay)
+		if err := Sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+	return nil, lastErr
+}
+
+// Backoff returns the delay before the attempt following attempt, preferring
+// a server-supplied Retry-After when one is given.
+func (p RetryPolicy) Backoff(attempt int, retryAfter time.Duration) time.Duration {
+	delay := p.BaseDelay * time.Duration(1<<uint(attempt-1)) // 1s, 2s, 4s...
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if retryAfter > 0 {
+		if p.MaxRetryAfter > 0 && retryAfter > p.MaxRetryAfter {
+			retryAfter = p.MaxRetryAfter
+		}
+		delay = retryAfter
+	}
+	return delay
+}
+
+// parseRetryAfter accepts both forms of the header: delay-seconds and HTTP-date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// Sleep waits for d or until ctx is done, whichever comes first.
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
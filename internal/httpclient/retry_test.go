@@ -0,0 +1,148 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var fastPolicy = RetryPolicy{Attempts: 3, BaseDelay: 10 * time.Millisecond, MaxRetryAfter: time.Second}
+
+func statusServer(t *testing.T, codes ...int) (*httptest.Server, *int) {
+	t.Helper()
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code := codes[len(codes)-1]
+		if calls < len(codes) {
+			code = codes[calls]
+		}
+		calls++
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestDo_StatusHandling(t *testing.T) {
+	tests := []struct {
+		name      string
+		codes     []int
+		wantCalls int
+		wantErr   bool
+		permanent bool
+	}{
+		{"success", []int{200}, 1, false, false},
+		{"503 then success", []int{503, 200}, 2, false, false},
+		{"429 then success", []int{429, 200}, 2, false, false},
+		{"408 then success", []int{408, 200}, 2, false, false},
+		{"404 not retried", []int{404}, 1, true, true},
+		{"403 not retried", []int{403}, 1, true, true},
+		{"500 exhausted", []int{500}, 3, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := statusServer(t, tt.codes...)
+			req, _ := http.NewRequest("GET", server.URL, nil)
+			resp, err := Do(context.Background(), http.DefaultClient, req, fastPolicy)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("Do() made %d calls, want %d", *calls, tt.wantCalls)
+			}
+			if err != nil && IsPermanent(err) != tt.permanent {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, IsPermanent(err), tt.permanent)
+			}
+		})
+	}
+}
+
+func TestDo_RetryAfter(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	start := time.Now()
+	resp, err := Do(context.Background(), http.DefaultClient, req, fastPolicy)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Do() returned after %v, want at least the 1s Retry-After", elapsed)
+	}
+}
+
+func TestDo_ContextCanceledDuringBackoff(t *testing.T) {
+	server, _ := statusServer(t, 503)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	slow := RetryPolicy{Attempts: 3, BaseDelay: 10 * time.Second}
+	start := time.Now()
+	_, err := Do(ctx, http.DefaultClient, req, slow)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Do() ignored cancellation, took %v", elapsed)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{"-1", 0},
+		{"garbage", 0},
+		{now.Add(30 * time.Second).Format(http.TimeFormat), 30 * time.Second},
+		{now.Add(-30 * time.Second).Format(http.TimeFormat), 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.in, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsPermanent(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{fmt.Errorf("network down"), false},
+		{&StatusError{StatusCode: 404}, true},
+		{&StatusError{StatusCode: 502}, false},
+		{fmt.Errorf("wrapped: %w", &StatusError{StatusCode: 401}), true},
+		{&PermanentError{Err: fmt.Errorf("bad")}, true},
+	}
+	for _, tt := range tests {
+		if got := IsPermanent(tt.err); got != tt.want {
+			t.Errorf("IsPermanent(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package ntp
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// setSystemClock sets the clock with settimeofday, which requires root.
+func setSystemClock(t time.Time) error {
+	tv := unix.NsecToTimeval(t.UnixNano())
+	return unix.Settimeofday(&tv)
+}
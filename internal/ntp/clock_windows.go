@@ -0,0 +1,32 @@
+//go:build windows
+
+package ntp
+
+import (
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procSetSystemTime = windows.NewLazySystemDLL("kernel32.dll").NewProc("SetSystemTime")
+
+// setSystemClock sets the clock with SetSystemTime, which enables
+// SeSystemtimePrivilege itself for administrators.
+func setSystemClock(t time.Time) error {
+	t = t.UTC()
+	st := windows.Systemtime{
+		Year:         uint16(t.Year()),
+		Month:        uint16(t.Month()),
+		DayOfWeek:    uint16(t.Weekday()),
+		Day:          uint16(t.Day()),
+		Hour:         uint16(t.Hour()),
+		Minute:       uint16(t.Minute()),
+		Second:       uint16(t.Second()),
+		Milliseconds: uint16(t.Nanosecond() / 1e6),
+	}
+	if r, _, err := procSetSystemTime.Call(uintptr(unsafe.Pointer(&st))); r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+// Package ntp is a minimal SNTP (RFC 4330) client used to check and correct
+// the system clock before configs are fetched over TLS.
+package ntp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/deck"
+)
+
+// DefaultTimeout bounds a single server query.
+const DefaultTimeout = 5 * time.Second
+
+// ntpEpochOffset is the number of seconds between 1900 and 1970.
+const ntpEpochOffset = 2208988800
+
+// Clock access, replaced in tests.
+var (
+	now      = time.Now
+	setClock = setSystemClock
+)
+
+var (
+	mu      sync.Mutex
+	servers = []string{"time.google.com"}
+)
+
+// SetServers sets the servers used by Sync and the clock_skew policy when
+// none are given.
+func SetServers(s []string) {
+	mu.Lock()
+	defer mu.Unlock()
+	servers = s
+}
+
+// Servers returns the configured servers.
+func Servers() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]string(nil), servers...)
+}
+
+// ParseServers splits a comma-separated -ntp_server value.
+func ParseServers(s string) []string {
+	var out []string
+	for _, server := range strings.Split(s, ",") {
+		if server = strings.TrimSpace(server); server != "" {
+			out = append(out, server)
+		}
+	}
+	return out
+}
+
+// Response is the result of one query. Offset is how far the local clock
+// is behind the server: adding it to the local time gives the server time.
+type Response struct {
+	Server  string
+	Offset  time.Duration
+	RTT     time.Duration
+	Stratum int
+}
+
+// Query asks server (host or host:port) for the time.
+func Query(ctx context.Context, server string, timeout time.Duration) (Response, error) {
+	r := Response{Server: server}
+	addr := server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		addr = net.JoinHostPort(server, "123")
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return r, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	req := make([]byte, 48)
+	req[0] = 0x23 // LI 0, version 4, mode 3 (client)
+	t1 := now()
+	putTimestamp(req[40:], t1)
+	if _, err := conn.Write(req); err != nil {
+		return r, err
+	}
+
+	resp := make([]byte, 48)
+	for {
+		n, err := conn.Read(resp)
+		if err != nil {
+			return r, err
+		}
+		// Ignore stray packets that do not answer this request.
+		if n >= 48 && binary.BigEndian.Uint64(resp[24:]) == binary.BigEndian.Uint64(req[40:]) {
+			break
+		}
+	}
+	t4 := now()
+
+	if resp[0]>>6 == 3 {
+		return r, errors.New("server clock is not synchronized")
+	}
+	if mode := resp[0] & 0x7; mode != 4 && mode != 5 {
+		return r, fmt.Errorf("unexpected NTP mode %d", mode)
+	}
+	r.Stratum = int(resp[1])
+	if r.Stratum == 0 {
+		return r, fmt.Errorf("server refused the request (kiss code %q)", strings.TrimRight(string(resp[12:16]), "\x00"))
+	}
+	t2, t3 := timestamp(resp[32:]), timestamp(resp[40:])
+	r.Offset = (t2.Sub(t1) + t3.Sub(t4)) / 2
+	r.RTT = t4.Sub(t1) - t3.Sub(t2)
+	return r, nil
+}
+
+// QueryAny queries each server in turn and returns the first answer.
+func QueryAny(ctx context.Context, servers []string, timeout time.Duration) (Response, error) {
+	if len(servers) == 0 {
+		return Response{}, errors.New("no NTP servers configured")
+	}
+	var errs []string
+	for _, s := range servers {
+		r, err := Query(ctx, s, timeout)
+		if err == nil {
+			return r, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", s, err))
+	}
+	return Response{}, fmt.Errorf("no NTP server answered: %s", strings.Join(errs, "; "))
+}
+
+// Sync queries servers and steps the system clock if it is off by more than
+// threshold. It returns the response it acted on.
+func Sync(ctx context.Context, servers []string, threshold time.Duration) (Response, error) {
+	r, err := QueryAny(ctx, servers, DefaultTimeout)
+	if err != nil {
+		return r, err
+	}
+	deck.Infof("Clock offset from %s: %s (round trip %s)", r.Server, r.Offset, r.RTT)
+	if abs(r.Offset) <= threshold {
+		return r, nil
+	}
+	target := now().Add(r.Offset)
+	if err := setClock(target); err != nil {
+		return r, fmt.Errorf("setting the clock to %s: %w", target.UTC().Format(time.RFC3339), err)
+	}
+	deck.Infof("Clock set to %s", target.UTC().Format(time.RFC3339))
+	return r, nil
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// timestamp decodes a 64-bit NTP timestamp. Seconds with the top bit clear
+// are taken to be in the era after the 2036 rollover, which covers 1968 to
+// 2104.
+func timestamp(b []byte) time.Time {
+	secs := int64(binary.BigEndian.Uint32(b))
+	if secs < 1<<31 {
+		secs += 1 << 32
+	}
+	secs -= ntpEpochOffset
+	frac := int64(binary.BigEndian.Uint32(b[4:]))
+	return time.Unix(secs, frac*1e9>>32)
+}
+
+// putTimestamp encodes t as a 64-bit NTP timestamp.
+func putTimestamp(b []byte, t time.Time) {
+	binary.BigEndian.PutUint32(b, uint32(t.Unix()+ntpEpochOffset))
+	binary.BigEndian.PutUint32(b[4:], uint32((int64(t.Nanosecond())<<32)/1e9))
+}
@@ -0,0 +1,158 @@
+package ntp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer answers SNTP requests with a clock skewed by offset. mutate
+// may alter each response before it is sent.
+func fakeServer(t *testing.T, offset time.Duration, mutate func([]byte)) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 48)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n < 48 {
+				continue
+			}
+			resp := make([]byte, 48)
+			resp[0] = 0x24 // LI 0, version 4, mode 4 (server)
+			resp[1] = 2    // stratum
+			copy(resp[24:32], buf[40:48])
+			serverNow := time.Now().Add(offset)
+			putTimestamp(resp[32:], serverNow)
+			putTimestamp(resp[40:], serverNow)
+			if mutate != nil {
+				mutate(resp)
+			}
+			conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestQuery(t *testing.T) {
+	for _, offset := range []time.Duration{0, time.Hour, -90 * 24 * time.Hour} {
+		server := fakeServer(t, offset, nil)
+		r, err := Query(context.Background(), server, time.Second)
+		if err != nil {
+			t.Fatalf("Query() error = %v", err)
+		}
+		if d := r.Offset - offset; d < -50*time.Millisecond || d > 50*time.Millisecond {
+			t.Errorf("Offset = %s, want about %s", r.Offset, offset)
+		}
+		if r.Stratum != 2 || r.RTT < 0 || r.RTT > time.Second {
+			t.Errorf("Query() = %+v", r)
+		}
+	}
+}
+
+func TestQuery_BadResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func([]byte)
+		wantErr string
+	}{
+		{"unsynchronized", func(b []byte) { b[0] |= 0xC0 }, "not synchronized"},
+		{"kiss of death", func(b []byte) { b[1] = 0; copy(b[12:], "RATE") }, `kiss code "RATE"`},
+		{"wrong mode", func(b []byte) { b[0] = 0x23 }, "unexpected NTP mode 3"},
+		{"wrong originate", func(b []byte) { binary.BigEndian.PutUint64(b[24:], 1) }, "timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeServer(t, 0, tt.mutate)
+			_, err := Query(context.Background(), server, 200*time.Millisecond)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Query() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQueryAny(t *testing.T) {
+	// nothing answers on a closed port
+	l, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	dead := l.LocalAddr().String()
+	l.Close()
+	good := fakeServer(t, time.Minute, nil)
+
+	r, err := QueryAny(context.Background(), []string{dead, good}, 200*time.Millisecond)
+	if err != nil || r.Server != good {
+		t.Errorf("QueryAny() = %+v, %v, want an answer from %s", r, err, good)
+	}
+	if _, err := QueryAny(context.Background(), []string{dead}, 200*time.Millisecond); err == nil || !strings.Contains(err.Error(), dead) {
+		t.Errorf("QueryAny() error = %v, want the failing server named", err)
+	}
+	if _, err := QueryAny(context.Background(), nil, time.Second); err == nil {
+		t.Error("QueryAny() expected error with no servers")
+	}
+}
+
+func TestSync(t *testing.T) {
+	orig := setClock
+	defer func() { setClock = orig }()
+	var set []time.Time
+	setClock = func(t time.Time) error {
+		set = append(set, t)
+		return nil
+	}
+
+	// within the threshold: left alone
+	if _, err := Sync(context.Background(), []string{fakeServer(t, 0, nil)}, 2*time.Second); err != nil || len(set) != 0 {
+		t.Errorf("Sync() = %v, set %v, want no change", err, set)
+	}
+
+	// a dead CMOS battery: the clock is years behind
+	skew := 3 * 365 * 24 * time.Hour
+	if _, err := Sync(context.Background(), []string{fakeServer(t, skew, nil)}, 2*time.Second); err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(set) != 1 {
+		t.Fatalf("clock set %d times, want 1", len(set))
+	}
+	if d := set[0].Sub(time.Now().Add(skew)); d < -time.Second || d > time.Second {
+		t.Errorf("clock set to %s, want about %s", set[0], time.Now().Add(skew))
+	}
+
+	setClock = func(time.Time) error { return errors.New("access denied") }
+	if _, err := Sync(context.Background(), []string{fakeServer(t, time.Hour, nil)}, time.Second); err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("Sync() error = %v, want the set failure", err)
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	for _, want := range []time.Time{
+		time.Date(2026, 10, 18, 12, 30, 15, 500_000_000, time.UTC),
+		time.Date(2040, 1, 1, 0, 0, 0, 0, time.UTC), // after the 2036 rollover
+	} {
+		b := make([]byte, 8)
+		putTimestamp(b, want)
+		if got := timestamp(b); got.Sub(want).Abs() > time.Microsecond {
+			t.Errorf("timestamp round trip = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestParseServers(t *testing.T) {
+	got := ParseServers(" time.google.com, ,pool.ntp.org:123")
+	if len(got) != 2 || got[0] != "time.google.com" || got[1] != "pool.ntp.org:123" {
+		t.Errorf("ParseServers() = %q", got)
+	}
+	if got := ParseServers(""); len(got) != 0 {
+		t.Errorf("ParseServers(\"\") = %q, want none", got)
+	}
+}
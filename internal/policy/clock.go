@@ -0,0 +1,59 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mjoliver/glazier-go/internal/ntp"
+)
+
+// DefaultMaxClockSkew is the Kerberos default tolerance, beyond which a
+// domain join fails.
+const DefaultMaxClockSkew = 5 * time.Minute
+
+// queryNTP asks servers for the time; tests replace it.
+var queryNTP = ntp.QueryAny
+
+// ClockSkewPolicy requires the local clock to be within Max of an NTP
+// server. With no Servers, those set with -ntp_server are used.
+type ClockSkewPolicy struct {
+	Max     time.Duration
+	Servers []string
+	Waiter
+}
+
+func (p *ClockSkewPolicy) Check(ctx context.Context) error {
+	return p.until(ctx, "clock_skew", func() error {
+		servers := p.Servers
+		if len(servers) == 0 {
+			servers = ntp.Servers()
+		}
+		r, err := queryNTP(ctx, servers, ntp.DefaultTimeout)
+		if err != nil {
+			return fmt.Errorf("policy clock_skew: %w", err)
+		}
+		skew := r.Offset
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > p.Max {
+			return fmt.Errorf("policy clock_skew: clock is off by %s from %s, more than %s", r.Offset.Round(time.Millisecond), r.Server, p.Max)
+		}
+		return nil
+	})
+}
+
+func init() {
+	Register("clock_skew", func(config interface{}) (Policy, error) {
+		cfg := struct {
+			Max        Duration   `yaml:"max"`
+			Servers    StringList `yaml:"servers"`
+			waitConfig `yaml:",inline"`
+		}{Max: Duration(DefaultMaxClockSkew)}
+		if err := Decode(config, &cfg); err != nil {
+			return nil, err
+		}
+		return &ClockSkewPolicy{Max: time.Duration(cfg.Max), Servers: cfg.Servers, Waiter: cfg.waiter()}, nil
+	})
+}
@@ -0,0 +1,68 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mjoliver/glazier-go/internal/ntp"
+)
+
+func TestClockSkewPolicy(t *testing.T) {
+	fakeClock(t, time.Now())
+	orig := queryNTP
+	defer func() { queryNTP = orig }()
+	ntp.SetServers([]string{"default.example.com"})
+	defer ntp.SetServers([]string{"time.google.com"})
+
+	var asked []string
+	offset := 90 * time.Second
+	queryNTP = func(ctx context.Context, servers []string, timeout time.Duration) (ntp.Response, error) {
+		asked = servers
+		if servers[0] == "down.example.com" {
+			return ntp.Response{}, errors.New("no NTP server answered")
+		}
+		return ntp.Response{Server: servers[0], Offset: offset}, nil
+	}
+
+	tests := []struct {
+		name      string
+		config    interface{}
+		wantErr   string
+		wantAsked string
+	}{
+		{"default max", nil, "", "default.example.com"},
+		{"too far", map[string]interface{}{"max": "1m"}, "clock is off by 1m30s from default.example.com, more than 1m0s", "default.example.com"},
+		{"own servers", map[string]interface{}{"max": 120, "servers": []interface{}{"ntp.corp.example.com"}}, "", "ntp.corp.example.com"},
+		{"unreachable", map[string]interface{}{"servers": "down.example.com"}, "policy clock_skew: no NTP server answered", "down.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPolicy("clock_skew", tt.config)
+			if err != nil {
+				t.Fatalf("NewPolicy() error = %v", err)
+			}
+			err = p.Check(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check() error = %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+			if len(asked) == 0 || asked[0] != tt.wantAsked {
+				t.Errorf("queried %v, want %s", asked, tt.wantAsked)
+			}
+		})
+	}
+
+	offset = -10 * time.Minute
+	if err := (&ClockSkewPolicy{Max: DefaultMaxClockSkew}).Check(context.Background()); err == nil || !strings.Contains(err.Error(), "off by -10m0s") {
+		t.Errorf("Check() error = %v, want a negative skew failure", err)
+	}
+	if _, err := NewPolicy("clock_skew", map[string]interface{}{"max": "never"}); err == nil {
+		t.Error("NewPolicy() expected error for an invalid max")
+	}
+}
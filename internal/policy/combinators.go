@@ -0,0 +1,158 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/deck"
+)
+
+// branch is a nested policy together with the name it was declared with.
+type branch struct {
+	Name   string
+	Policy Policy
+}
+
+func (b branch) String() string {
+	return b.Name
+}
+
+// AnyOfPolicy passes if at least one of its branches passes.
+type AnyOfPolicy struct {
+	Branches []branch
+}
+
+func (p *AnyOfPolicy) Check(ctx context.Context) error {
+	var failures []string
+	for i, b := range p.Branches {
+		err := b.Policy.Check(ctx)
+		if err == nil {
+			deck.Infof("Policy any_of: branch %d (%s) matched", i, b)
+			return nil
+		}
+		failures = append(failures, fmt.Sprintf("[%d] %s: %v", i, b, err))
+	}
+	return fmt.Errorf("policy any_of: no branch matched: %s", strings.Join(failures, "; "))
+}
+
+// AllOfPolicy passes if every branch passes. A policy list is already an
+// implicit all_of; this is for nesting inside any_of and not.
+type AllOfPolicy struct {
+	Branches []branch
+}
+
+func (p *AllOfPolicy) Check(ctx context.Context) error {
+	for i, b := range p.Branches {
+		if err := b.Policy.Check(ctx); err != nil {
+			return fmt.Errorf("policy all_of: branch %d (%s) failed: %w", i, b, err)
+		}
+	}
+	return nil
+}
+
+// NotPolicy passes if its branch fails. A branch that could not be checked,
+// because its fact is unknown or ctx is done, fails the policy instead.
+type NotPolicy struct {
+	Branch branch
+}
+
+func (p *NotPolicy) Check(ctx context.Context) error {
+	if err := p.Branch.Policy.Check(ctx); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("policy not: %s: %w", p.Branch, ctxErr)
+		}
+		var unknown *UnknownFactError
+		if errors.As(err, &unknown) {
+			return fmt.Errorf("policy not: %s could not be checked: %w", p.Branch, err)
+		}
+		deck.Infof("Policy not: %s failed as required: %v", p.Branch, err)
+		return nil
+	}
+	return fmt.Errorf("policy not: %s passed", p.Branch)
+}
+
+// parseEntry splits a policy list entry into its name and config. An entry
+// is either a bare name or a map with exactly one key.
+func parseEntry(entry interface{}) (string, interface{}, error) {
+	switch v := entry.(type) {
+	case string:
+		return v, nil, nil
+	case map[string]interface{}:
+		if len(v) != 1 {
+			return "", nil, fmt.Errorf("policy entry must have exactly one key, got %d", len(v))
+		}
+		for k, val := range v {
+			return k, val, nil
+		}
+	}
+	return "", nil, fmt.Errorf("invalid policy format: %v", entry)
+}
+
+// newBranch builds the nested policy for a combinator. where names its
+// position (e.g. "any_of[1]") so errors in deeply nested definitions can be
+// found.
+func newBranch(where string, entry interface{}) (branch, error) {
+	name, config, err := parseEntry(entry)
+	if err != nil {
+		return branch{}, fmt.Errorf("%s: %w", where, err)
+	}
+	p, err := NewPolicy(name, config)
+	if err != nil {
+		// Combinator errors already start with their own position.
+		if _, ok := Registry[name]; ok && !combinators[name] {
+			return branch{}, fmt.Errorf("%s: %s: %w", where, name, err)
+		}
+		return branch{}, fmt.Errorf("%s: %w", where, err)
+	}
+	return branch{Name: name, Policy: p}, nil
+}
+
+// newBranches builds the branches of any_of or all_of from a list of
+// policy entries.
+func newBranches(kind string, config interface{}) ([]branch, error) {
+	list, ok := config.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf("%s requires a non-empty list of policies", kind)
+	}
+	branches := make([]branch, 0, len(list))
+	for i, entry := range list {
+		b, err := newBranch(fmt.Sprintf("%s[%d]", kind, i), entry)
+		if err != nil {
+			return nil, err
+		}
+		branches = append(branches, b)
+	}
+	return branches, nil
+}
+
+// combinators are the policies that nest other policies.
+var combinators = map[string]bool{"any_of": true, "all_of": true, "not": true}
+
+func init() {
+	Register("any_of", func(config interface{}) (Policy, error) {
+		branches, err := newBranches("any_of", config)
+		if err != nil {
+			return nil, err
+		}
+		return &AnyOfPolicy{Branches: branches}, nil
+	})
+	Register("all_of", func(config interface{}) (Policy, error) {
+		branches, err := newBranches("all_of", config)
+		if err != nil {
+			return nil, err
+		}
+		return &AllOfPolicy{Branches: branches}, nil
+	})
+	Register("not", func(config interface{}) (Policy, error) {
+		if config == nil {
+			return nil, fmt.Errorf("not requires a policy")
+		}
+		b, err := newBranch("not", config)
+		if err != nil {
+			return nil, err
+		}
+		return &NotPolicy{Branch: b}, nil
+	})
+}
@@ -0,0 +1,292 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/deck"
+	"github.com/mjoliver/glazier-go/internal/httpclient"
+)
+
+// powerStatus describes the machine's power source.
+type powerStatus struct {
+	HasBattery     bool
+	ACOnline       bool
+	ACUnknown      bool // the power source could not be determined
+	BatteryPercent int  // -1 if unknown
+}
+
+// Environment readers, replaced in tests.
+var (
+	getPowerStatus = readPowerStatus
+	getFreeSpace   = freeSpace
+	now            = time.Now
+	sleep          = httpclient.Sleep
+)
+
+// DefaultPollInterval is how often a waiting policy re-checks.
+const DefaultPollInterval = 15 * time.Second
+
+// Waiter lets a policy wait for its condition instead of failing at once.
+// With a zero Wait the condition is checked only once.
+type Waiter struct {
+	Wait time.Duration
+	Poll time.Duration
+}
+
+// until runs check until it succeeds, w.Wait has passed or ctx is done,
+// returning the last failure.
+func (w Waiter) until(ctx context.Context, name string, check func() error) error {
+	err := check()
+	if err == nil || w.Wait <= 0 {
+		return err
+	}
+	poll := w.Poll
+	if poll <= 0 {
+		poll = DefaultPollInterval
+	}
+	deadline := now().Add(w.Wait)
+	deck.Infof("Policy %s: waiting up to %s: %v", name, w.Wait, err)
+	for now().Before(deadline) {
+		if serr := sleep(ctx, poll); serr != nil {
+			return fmt.Errorf("%w (stopped waiting: %v)", err, serr)
+		}
+		if err = check(); err == nil {
+			deck.Infof("Policy %s: condition met", name)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w (waited %s)", err, w.Wait)
+}
+
+// waitConfig holds the wait and poll keys shared by waiting policies. It
+// is inlined into their config structs.
+type waitConfig struct {
+	Wait Duration `yaml:"wait"`
+	Poll Duration `yaml:"poll"`
+}
+
+func (c waitConfig) waiter() Waiter {
+	return Waiter{Wait: time.Duration(c.Wait), Poll: time.Duration(c.Poll)}
+}
+
+// ACPowerPolicy requires the machine to run on mains power. Machines
+// without a battery always pass.
+type ACPowerPolicy struct {
+	AllowUnknown bool // pass if a battery's power source cannot be read
+	Waiter
+}
+
+func (p *ACPowerPolicy) Check(ctx context.Context) error {
+	return p.until(ctx, "ac_power", func() error {
+		s, err := getPowerStatus()
+		if err != nil {
+			deck.Warningf("Policy ac_power: power state unknown, skipping check: %v", err)
+			return nil
+		}
+		if s.HasBattery && s.ACUnknown {
+			return unknownFact("ac_power", "power source", p.AllowUnknown)
+		}
+		if s.HasBattery && !s.ACOnline {
+			return fmt.Errorf("policy ac_power: running on battery")
+		}
+		return nil
+	})
+}
+
+// MinBatteryPolicy requires the battery to be charged to at least Percent.
+// Machines without a battery always pass.
+type MinBatteryPolicy struct {
+	Percent int
+	Waiter
+}
+
+func (p *MinBatteryPolicy) Check(ctx context.Context) error {
+	return p.until(ctx, "min_battery_percent", func() error {
+		s, err := getPowerStatus()
+		if err != nil || (s.HasBattery && s.BatteryPercent < 0) {
+			deck.Warningf("Policy min_battery_percent: battery level unknown, skipping check")
+			return nil
+		}
+		if s.HasBattery && s.BatteryPercent < p.Percent {
+			return fmt.Errorf("policy min_battery_percent: battery at %d%%, below the required %d%%", s.BatteryPercent, p.Percent)
+		}
+		return nil
+	})
+}
+
+// MinFreeSpacePolicy requires at least GB gigabytes free on the volume
+// holding Path.
+type MinFreeSpacePolicy struct {
+	Path string
+	GB   int64
+	Waiter
+}
+
+func (p *MinFreeSpacePolicy) Check(ctx context.Context) error {
+	return p.until(ctx, "min_free_space", func() error {
+		free, err := getFreeSpace(p.Path)
+		if err != nil {
+			return fmt.Errorf("policy min_free_space: %s: %w", p.Path, err)
+		}
+		if gb := int64(free / 1e9); gb < p.GB {
+			return fmt.Errorf("policy min_free_space: %d GB free on %s, below the required %d GB", gb, p.Path, p.GB)
+		}
+		return nil
+	})
+}
+
+// TimeWindowPolicy only passes during the given local hours and weekdays.
+// A window whose end is before its start runs past midnight, and the hours
+// after midnight belong to the previous day's window.
+type TimeWindowPolicy struct {
+	Start, End time.Duration  // since midnight; equal means all day
+	Days       []time.Weekday // empty means every day
+	Waiter
+}
+
+func (p *TimeWindowPolicy) Check(ctx context.Context) error {
+	return p.until(ctx, "time_window", func() error {
+		t := now()
+		of := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+		day := t.Weekday()
+		inHours := true
+		switch {
+		case p.Start < p.End:
+			inHours = of >= p.Start && of < p.End
+		case p.Start > p.End:
+			inHours = of >= p.Start || of < p.End
+			if of < p.End {
+				day = (day + 6) % 7
+			}
+		}
+		if !inHours {
+			return fmt.Errorf("policy time_window: %s is outside %s-%s", t.Format("15:04"), clock(p.Start), clock(p.End))
+		}
+		if len(p.Days) > 0 && !containsDay(p.Days, day) {
+			return fmt.Errorf("policy time_window: %s is not an allowed day %v", day, p.Days)
+		}
+		return nil
+	})
+}
+
+func containsDay(days []time.Weekday, d time.Weekday) bool {
+	for _, day := range days {
+		if day == d {
+			return true
+		}
+	}
+	return false
+}
+
+func clock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// parseClock parses "HH:MM" into a duration since midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseWeekday accepts English day names or their first three letters.
+func parseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) >= 3 {
+		if d, ok := weekdays[s[:3]]; ok && strings.HasPrefix(strings.ToLower(d.String()), s) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid day %q", s)
+}
+
+func init() {
+	Register("ac_power", func(config interface{}) (Policy, error) {
+		var cfg struct {
+			AllowUnknown bool `yaml:"allow_unknown"`
+			waitConfig   `yaml:",inline"`
+		}
+		if err := Decode(config, &cfg); err != nil {
+			return nil, err
+		}
+		return &ACPowerPolicy{AllowUnknown: cfg.AllowUnknown, Waiter: cfg.waiter()}, nil
+	})
+	Register("min_battery_percent", func(config interface{}) (Policy, error) {
+		var cfg struct {
+			Percent    int `yaml:"percent"`
+			waitConfig `yaml:",inline"`
+		}
+		if err := decodeShorthand(config, "percent", &cfg); err != nil {
+			return nil, err
+		}
+		if cfg.Percent < 1 || cfg.Percent > 100 {
+			return nil, fmt.Errorf("requires a percentage from 1 to 100")
+		}
+		return &MinBatteryPolicy{Percent: cfg.Percent, Waiter: cfg.waiter()}, nil
+	})
+	Register("min_free_space", func(config interface{}) (Policy, error) {
+		var cfg struct {
+			Path       string `yaml:"path"`
+			GB         int64  `yaml:"gb"`
+			waitConfig `yaml:",inline"`
+		}
+		if err := Decode(config, &cfg); err != nil {
+			return nil, err
+		}
+		if cfg.GB <= 0 {
+			return nil, fmt.Errorf("requires a positive size in GB, e.g. {path: 'C:\\', gb: 40}")
+		}
+		if cfg.Path == "" {
+			cfg.Path = defaultFreeSpacePath()
+		}
+		return &MinFreeSpacePolicy{Path: cfg.Path, GB: cfg.GB, Waiter: cfg.waiter()}, nil
+	})
+	Register("time_window", newTimeWindowPolicy)
+}
+
+// newTimeWindowPolicy builds a time_window policy from its YAML config.
+func newTimeWindowPolicy(config interface{}) (Policy, error) {
+	var cfg struct {
+		Hours      string     `yaml:"hours"`
+		Days       StringList `yaml:"days"`
+		waitConfig `yaml:",inline"`
+	}
+	if err := Decode(config, &cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Hours == "" && len(cfg.Days) == 0 {
+		return nil, fmt.Errorf("requires hours, days or both")
+	}
+	p := &TimeWindowPolicy{Waiter: cfg.waiter()}
+	if cfg.Hours != "" {
+		start, end, ok := strings.Cut(cfg.Hours, "-")
+		if !ok {
+			return nil, fmt.Errorf("hours must be of the form HH:MM-HH:MM")
+		}
+		var err error
+		if p.Start, err = parseClock(start); err != nil {
+			return nil, err
+		}
+		if p.End, err = parseClock(end); err != nil {
+			return nil, err
+		}
+	}
+	for _, d := range cfg.Days {
+		day, err := parseWeekday(d)
+		if err != nil {
+			return nil, err
+		}
+		p.Days = append(p.Days, day)
+	}
+	return p, nil
+}
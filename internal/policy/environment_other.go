@@ -0,0 +1,76 @@
+//go:build !windows
+
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// powerSupplyDir lists the kernel's power supplies; tests point it at a
+// fake tree.
+var powerSupplyDir = "/sys/class/power_supply"
+
+// readPowerStatus reads the power source from /sys/class/power_supply.
+func readPowerStatus() (powerStatus, error) {
+	status := powerStatus{BatteryPercent: -1}
+	entries, err := os.ReadDir(powerSupplyDir)
+	if err != nil {
+		return status, err
+	}
+	mains, discharging := false, false
+	for _, e := range entries {
+		dir := filepath.Join(powerSupplyDir, e.Name())
+		switch readTrimmed(filepath.Join(dir, "type")) {
+		case "Mains":
+			mains = true
+			if readTrimmed(filepath.Join(dir, "online")) == "1" {
+				status.ACOnline = true
+			}
+		case "Battery":
+			// Peripheral batteries (mice, keyboards) report scope Device.
+			if readTrimmed(filepath.Join(dir, "scope")) == "Device" {
+				continue
+			}
+			status.HasBattery = true
+			if readTrimmed(filepath.Join(dir, "status")) == "Discharging" {
+				discharging = true
+			}
+			if n, err := strconv.Atoi(readTrimmed(filepath.Join(dir, "capacity"))); err == nil {
+				status.BatteryPercent = n
+			}
+		}
+	}
+	if !mains {
+		// Without a mains supply to ask, assume AC unless discharging.
+		status.ACOnline = !discharging
+	}
+	return status, nil
+}
+
+func readTrimmed(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// freeSpace returns the bytes available to unprivileged users on the
+// filesystem holding path.
+func freeSpace(path string) (uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return st.Bavail * uint64(st.Bsize), nil
+}
+
+// defaultFreeSpacePath is the root filesystem.
+func defaultFreeSpacePath() string {
+	return "/"
+}
@@ -0,0 +1,50 @@
+//go:build !windows
+
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadPowerStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  powerStatus
+	}{
+		{"desktop", map[string]string{"AC/type": "Mains", "AC/online": "1"}, powerStatus{ACOnline: true, BatteryPercent: -1}},
+		{"laptop on battery", map[string]string{"AC/type": "Mains", "AC/online": "0", "BAT0/type": "Battery", "BAT0/capacity": "42"}, powerStatus{HasBattery: true, BatteryPercent: 42}},
+		{"laptop charging", map[string]string{"ADP1/type": "Mains", "ADP1/online": "1", "BAT0/type": "Battery", "BAT0/capacity": "80"}, powerStatus{HasBattery: true, ACOnline: true, BatteryPercent: 80}},
+		{"no mains, discharging", map[string]string{"BAT0/type": "Battery", "BAT0/status": "Discharging", "BAT0/capacity": "90"}, powerStatus{HasBattery: true, BatteryPercent: 90}},
+		{"mouse battery", map[string]string{"AC/type": "Mains", "AC/online": "1", "hid-mouse/type": "Battery", "hid-mouse/scope": "Device", "hid-mouse/capacity": "5"}, powerStatus{ACOnline: true, BatteryPercent: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				p := filepath.Join(dir, name)
+				os.MkdirAll(filepath.Dir(p), 0755)
+				os.WriteFile(p, []byte(content+"\n"), 0644)
+			}
+			orig := powerSupplyDir
+			powerSupplyDir = dir
+			defer func() { powerSupplyDir = orig }()
+
+			got, err := readPowerStatus()
+			if err != nil || got != tt.want {
+				t.Errorf("readPowerStatus() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFreeSpace(t *testing.T) {
+	if n, err := freeSpace(t.TempDir()); err != nil || n == 0 {
+		t.Errorf("freeSpace() = %d, %v", n, err)
+	}
+	if _, err := freeSpace(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("freeSpace() expected error for a missing path")
+	}
+}
@@ -0,0 +1,217 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock replaces now and sleep so waits finish instantly.
+func fakeClock(t *testing.T, start time.Time) *time.Time {
+	t.Helper()
+	origNow, origSleep := now, sleep
+	t.Cleanup(func() { now, sleep = origNow, origSleep })
+	current := start
+	now = func() time.Time { return current }
+	sleep = func(_ context.Context, d time.Duration) error { current = current.Add(d); return nil }
+	return &current
+}
+
+func TestWaiter(t *testing.T) {
+	clock := fakeClock(t, time.Date(2026, 3, 2, 12, 0, 0, 0, time.Local))
+	start := *clock
+
+	calls := 0
+	w := Waiter{Wait: time.Minute, Poll: 10 * time.Second}
+	err := w.until(context.Background(), "test", func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 || clock.Sub(start) != 20*time.Second {
+		t.Errorf("until() = %v after %d calls and %s", err, calls, clock.Sub(start))
+	}
+
+	calls = 0
+	err = w.until(context.Background(), "test", func() error { calls++; return errors.New("never") })
+	if err == nil || !strings.Contains(err.Error(), "never (waited 1m0s)") || calls != 7 {
+		t.Errorf("until() = %v after %d calls, want a timeout after 7", err, calls)
+	}
+
+	calls = 0
+	if err := (Waiter{}).until(context.Background(), "test", func() error { calls++; return errors.New("no") }); err == nil || calls != 1 {
+		t.Errorf("until() without wait = %v after %d calls, want one failing check", err, calls)
+	}
+}
+
+func TestPowerPolicies(t *testing.T) {
+	fakeClock(t, time.Now())
+	orig := getPowerStatus
+	defer func() { getPowerStatus = orig }()
+
+	onBattery := powerStatus{HasBattery: true, BatteryPercent: 35}
+	charging := powerStatus{HasBattery: true, ACOnline: true, BatteryPercent: 35}
+	desktop := powerStatus{BatteryPercent: -1}
+	sourceUnknown := powerStatus{HasBattery: true, ACUnknown: true, BatteryPercent: 35}
+
+	tests := []struct {
+		name    string
+		status  powerStatus
+		err     error
+		policy  string
+		config  interface{}
+		wantErr string
+	}{
+		{"ac", charging, nil, "ac_power", nil, ""},
+		{"battery", onBattery, nil, "ac_power", nil, "running on battery"},
+		{"battery after wait", onBattery, nil, "ac_power", map[string]interface{}{"wait": "10m"}, "running on battery (waited 10m0s)"},
+		{"no battery", desktop, nil, "ac_power", nil, ""},
+		{"unknown", powerStatus{}, errors.New("no sysfs"), "ac_power", nil, ""},
+		{"source unknown", sourceUnknown, nil, "ac_power", nil, "power source unknown"},
+		{"source unknown after wait", sourceUnknown, nil, "ac_power", map[string]interface{}{"wait": "10m"}, "power source unknown; set allow_unknown to skip the check (waited 10m0s)"},
+		{"source unknown allowed", sourceUnknown, nil, "ac_power", map[string]interface{}{"allow_unknown": true}, ""},
+		{"level", onBattery, nil, "min_battery_percent", 30, ""},
+		{"level low", onBattery, nil, "min_battery_percent", map[string]interface{}{"percent": 50}, "battery at 35%, below the required 50%"},
+		{"level no battery", desktop, nil, "min_battery_percent", 50, ""},
+		{"level unknown", powerStatus{HasBattery: true, BatteryPercent: -1}, nil, "min_battery_percent", 50, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getPowerStatus = func() (powerStatus, error) { return tt.status, tt.err }
+			p, err := NewPolicy(tt.policy, tt.config)
+			if err != nil {
+				t.Fatalf("NewPolicy() error = %v", err)
+			}
+			err = p.Check(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check() error = %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestACPowerPolicy_WaitsForCharger(t *testing.T) {
+	clock := fakeClock(t, time.Now())
+	start := *clock
+	orig := getPowerStatus
+	defer func() { getPowerStatus = orig }()
+	getPowerStatus = func() (powerStatus, error) {
+		// plugged in after two minutes
+		return powerStatus{HasBattery: true, ACOnline: clock.Sub(start) >= 2*time.Minute}, nil
+	}
+
+	p, _ := NewPolicy("ac_power", map[string]interface{}{"wait": "10m", "poll": 30})
+	if err := p.Check(context.Background()); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+	if waited := clock.Sub(start); waited != 2*time.Minute {
+		t.Errorf("waited %s, want 2m", waited)
+	}
+}
+
+func TestMinFreeSpacePolicy(t *testing.T) {
+	orig := getFreeSpace
+	defer func() { getFreeSpace = orig }()
+	getFreeSpace = func(path string) (uint64, error) {
+		if path == "missing" {
+			return 0, errors.New("no such volume")
+		}
+		return 50e9, nil
+	}
+
+	tests := []struct {
+		config  map[string]interface{}
+		wantErr string
+	}{
+		{map[string]interface{}{"gb": 40}, ""},
+		{map[string]interface{}{"path": "D:\\", "gb": 64}, `50 GB free on D:\, below the required 64 GB`},
+		{map[string]interface{}{"path": "missing", "gb": 1}, "no such volume"},
+	}
+	for _, tt := range tests {
+		p, err := NewPolicy("min_free_space", tt.config)
+		if err != nil {
+			t.Fatalf("NewPolicy() error = %v", err)
+		}
+		err = p.Check(context.Background())
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("Check(%v) error = %v", tt.config, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Check(%v) error = %v, want %q", tt.config, err, tt.wantErr)
+		}
+	}
+	if p, _ := NewPolicy("min_free_space", map[string]interface{}{"gb": 1}); p.(*MinFreeSpacePolicy).Path != defaultFreeSpacePath() {
+		t.Errorf("Path = %q, want the system drive", p.(*MinFreeSpacePolicy).Path)
+	}
+}
+
+func TestTimeWindowPolicy(t *testing.T) {
+	// 2026-03-02 is a Monday
+	at := func(day, hour, min int) time.Time { return time.Date(2026, 3, day, hour, min, 0, 0, time.Local) }
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		now     time.Time
+		wantErr string
+	}{
+		{"inside", map[string]interface{}{"hours": "09:00-17:00"}, at(2, 12, 0), ""},
+		{"end is exclusive", map[string]interface{}{"hours": "09:00-17:00"}, at(2, 17, 0), "17:00 is outside 09:00-17:00"},
+		{"overnight late", map[string]interface{}{"hours": "22:00-06:00"}, at(2, 23, 30), ""},
+		{"overnight early", map[string]interface{}{"hours": "22:00-06:00"}, at(2, 5, 59), ""},
+		{"overnight outside", map[string]interface{}{"hours": "22:00-06:00"}, at(2, 12, 0), "outside 22:00-06:00"},
+		{"day", map[string]interface{}{"days": []interface{}{"mon", "Tuesday"}}, at(3, 12, 0), ""},
+		{"wrong day", map[string]interface{}{"days": []interface{}{"sat", "sun"}}, at(2, 12, 0), "Monday is not an allowed day"},
+		{"overnight belongs to previous day", map[string]interface{}{"hours": "22:00-06:00", "days": []interface{}{"fri"}}, at(7, 2, 0), ""},
+		{"waits for window", map[string]interface{}{"hours": "18:00-20:00", "wait": "8h", "poll": "5m"}, at(2, 12, 0), ""},
+		{"wait too short", map[string]interface{}{"hours": "18:00-20:00", "wait": "1h", "poll": "5m"}, at(2, 12, 0), "(waited 1h0m0s)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClock(t, tt.now)
+			p, err := NewPolicy("time_window", tt.config)
+			if err != nil {
+				t.Fatalf("NewPolicy() error = %v", err)
+			}
+			err = p.Check(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check() error = %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnvironmentPolicies_Invalid(t *testing.T) {
+	tests := []struct {
+		policy string
+		config interface{}
+	}{
+		{"ac_power", map[string]interface{}{"wait": "soon"}},
+		{"ac_power", map[string]interface{}{"poll": "-1s"}},
+		{"min_battery_percent", nil},
+		{"min_battery_percent", 101},
+		{"min_free_space", map[string]interface{}{"path": "C:\\"}},
+		{"time_window", nil},
+		{"time_window", map[string]interface{}{"hours": "9-17"}},
+		{"time_window", map[string]interface{}{"hours": "09:00"}},
+		{"time_window", map[string]interface{}{"days": []interface{}{"someday"}}},
+		{"time_window", map[string]interface{}{"days": []interface{}{"mo"}}},
+	}
+	for _, tt := range tests {
+		if _, err := NewPolicy(tt.policy, tt.config); err == nil {
+			t.Errorf("NewPolicy(%s, %v) expected error", tt.policy, tt.config)
+		}
+	}
+}
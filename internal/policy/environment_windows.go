@@ -0,0 +1,62 @@
+//go:build windows
+
+package policy
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procGetSystemPowerStatus = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetSystemPowerStatus")
+
+// systemPowerStatus mirrors SYSTEM_POWER_STATUS.
+type systemPowerStatus struct {
+	ACLineStatus        byte
+	BatteryFlag         byte
+	BatteryLifePercent  byte
+	SystemStatusFlag    byte
+	BatteryLifeTime     uint32
+	BatteryFullLifeTime uint32
+}
+
+// readPowerStatus reads the power source from GetSystemPowerStatus.
+func readPowerStatus() (powerStatus, error) {
+	var s systemPowerStatus
+	if r, _, err := procGetSystemPowerStatus.Call(uintptr(unsafe.Pointer(&s))); r == 0 {
+		return powerStatus{}, err
+	}
+	status := powerStatus{
+		HasBattery:     s.BatteryFlag != 128 && s.BatteryFlag != 255, // 128: no system battery, 255: unknown
+		ACOnline:       s.ACLineStatus == 1,
+		ACUnknown:      s.ACLineStatus == 255, // common on VMs
+		BatteryPercent: int(s.BatteryLifePercent),
+	}
+	if s.BatteryLifePercent == 255 {
+		status.BatteryPercent = -1
+	}
+	return status, nil
+}
+
+// freeSpace returns the bytes available to this process on the volume
+// holding path.
+func freeSpace(path string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, &total, &totalFree); err != nil {
+		return 0, err
+	}
+	return free, nil
+}
+
+// defaultFreeSpacePath is the system drive.
+func defaultFreeSpacePath() string {
+	if d := os.Getenv("SystemDrive"); d != "" {
+		return d + `\`
+	}
+	return `C:\`
+}
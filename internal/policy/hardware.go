@@ -0,0 +1,414 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/google/deck"
+	"github.com/mjoliver/glazier-go/internal/facts"
+)
+
+// Hardware requirement policies read the shared machine facts. A fact that
+// could not be collected is logged and passes, like device_model, so an
+// unreadable WMI class does not block every build. The tpm and secure_boot
+// security gates are the exception: they fail when the fact is unknown
+// unless allow_unknown is set.
+
+// MinMemoryPolicy requires at least GB of RAM, compared against the
+// rounded ram_gb fact.
+type MinMemoryPolicy struct {
+	GB int64
+}
+
+func (p *MinMemoryPolicy) Check(ctx context.Context) error {
+	f, err := currentFacts(ctx)
+	if err != nil {
+		return fmt.Errorf("policy min_memory: %w", err)
+	}
+	gb, ok := f.Int("ram_gb")
+	if !ok {
+		deck.Warningf("Policy min_memory: memory size unknown, skipping check")
+		return nil
+	}
+	if gb < p.GB {
+		return fmt.Errorf("policy min_memory: %d GB of RAM is below the required %d GB", gb, p.GB)
+	}
+	return nil
+}
+
+// MinDiskSizePolicy requires a disk of at least GB gigabytes. Disk sizes
+// are decimal, as printed on the drive, so a 256 GB SSD passes gb: 256.
+// With Disk set, only that disk number (as in disk_id) is checked, which
+// needs Windows disk names; otherwise the largest disk is, which a small OS
+// disk next to a large data disk would pass.
+type MinDiskSizePolicy struct {
+	GB   int64
+	Disk *int
+}
+
+func (p *MinDiskSizePolicy) Check(ctx context.Context) error {
+	f, err := currentFacts(ctx)
+	if err != nil {
+		return fmt.Errorf("policy min_disk_size: %w", err)
+	}
+	disks, ok := f["disks"].([]interface{})
+	if !ok || len(disks) == 0 {
+		deck.Warningf("Policy min_disk_size: no disks found, skipping check")
+		return nil
+	}
+	var size int64
+	found := false
+	for _, d := range disks {
+		disk := facts.Facts(asMap(d))
+		n, ok := disk.Int("size_bytes")
+		if !ok {
+			continue
+		}
+		if p.Disk != nil {
+			if num, ok := diskNumber(disk.String("name")); ok && num == *p.Disk {
+				size, found = n, true
+				break
+			}
+			continue
+		}
+		if n > size {
+			size, found = n, true
+		}
+	}
+	switch {
+	case p.Disk == nil && !found:
+		deck.Warningf("Policy min_disk_size: disk sizes unknown, skipping check")
+		return nil
+	case p.Disk != nil && !found:
+		return fmt.Errorf("policy min_disk_size: disk %d not found", *p.Disk)
+	}
+	which := "largest disk"
+	if p.Disk != nil {
+		which = fmt.Sprintf("disk %d", *p.Disk)
+	}
+	if gb := size / 1e9; gb < p.GB {
+		return fmt.Errorf("policy min_disk_size: %s is %d GB, below the required %d GB", which, gb, p.GB)
+	}
+	return nil
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+// numberedDisks reports whether the disk facts are named by Windows disk
+// number, which min_disk_size's disk key needs. Other platforms name disks
+// sda, nvme0n1 and so on, in no fixed order. Tests override it.
+var numberedDisks = runtime.GOOS == "windows"
+
+// diskNumber extracts N from a Windows disk name such as
+// \\.\PHYSICALDRIVE<N>.
+func diskNumber(name string) (int, bool) {
+	const prefix = `\\.\PHYSICALDRIVE`
+	if len(name) <= len(prefix) || !strings.EqualFold(name[:len(prefix)], prefix) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(name[len(prefix):])
+	return n, err == nil
+}
+
+// archAliases maps common architecture names to GOARCH values.
+var archAliases = map[string]string{
+	"x64":     "amd64",
+	"x86_64":  "amd64",
+	"x86":     "386",
+	"i386":    "386",
+	"aarch64": "arm64",
+}
+
+func normalizeArch(s string) string {
+	s = strings.ToLower(s)
+	if alias, ok := archAliases[s]; ok {
+		return alias
+	}
+	return s
+}
+
+// CPUArchPolicy checks the machine architecture ("amd64", "arm64", ...).
+type CPUArchPolicy struct {
+	AllowedArchs []string
+}
+
+func (p *CPUArchPolicy) Check(ctx context.Context) error {
+	if len(p.AllowedArchs) == 0 {
+		return nil
+	}
+	f, err := currentFacts(ctx)
+	if err != nil {
+		return fmt.Errorf("policy cpu_arch: %w", err)
+	}
+	arch := f.String("arch")
+	if arch == "" {
+		deck.Warningf("Policy cpu_arch: architecture unknown, skipping check")
+		return nil
+	}
+	for _, allowed := range p.AllowedArchs {
+		if normalizeArch(allowed) == normalizeArch(arch) {
+			return nil
+		}
+	}
+	return fmt.Errorf("policy cpu_arch: architecture %q not in allowed list %v", arch, p.AllowedArchs)
+}
+
+// TPMPolicy requires a TPM, optionally of at least MinVersion ("2.0").
+type TPMPolicy struct {
+	MinVersion   string
+	AllowUnknown bool // pass if the TPM state cannot be read
+}
+
+func (p *TPMPolicy) Check(ctx context.Context) error {
+	f, err := currentFacts(ctx)
+	if err != nil {
+		return fmt.Errorf("policy tpm: %w", err)
+	}
+	present, ok := f.Bool("tpm")
+	if !ok {
+		return unknownFact("tpm", "TPM state", p.AllowUnknown)
+	}
+	if !present {
+		return fmt.Errorf("policy tpm: no TPM found")
+	}
+	if p.MinVersion == "" {
+		return nil
+	}
+	version := f.String("tpm_version")
+	if version == "" {
+		return unknownFact("tpm", "TPM version", p.AllowUnknown)
+	}
+	if compareVersions(version, p.MinVersion) < 0 {
+		return fmt.Errorf("policy tpm: TPM version %q is below the required %s", version, p.MinVersion)
+	}
+	return nil
+}
+
+// compareVersions compares dotted numeric versions such as "1.2" and "2.0".
+// Missing or non-numeric parts count as 0.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			fmt.Sscan(as[i], &x)
+		}
+		if i < len(bs) {
+			fmt.Sscan(bs[i], &y)
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// UnknownFactError reports that a policy failed because the fact it checks
+// could not be read, rather than because the fact did not match.
+type UnknownFactError struct {
+	Policy string
+	What   string // e.g. "TPM state"
+}
+
+func (e *UnknownFactError) Error() string {
+	return fmt.Sprintf("policy %s: %s unknown; set allow_unknown to skip the check", e.Policy, e.What)
+}
+
+// unknownFact handles a security gate whose fact could not be read: it
+// fails unless allowUnknown is set.
+func unknownFact(policy, what string, allowUnknown bool) error {
+	if allowUnknown {
+		deck.Warningf("Policy %s: %s unknown, skipping check (allow_unknown)", policy, what)
+		return nil
+	}
+	return &UnknownFactError{Policy: policy, What: what}
+}
+
+// SecureBootPolicy requires Secure Boot to be enabled.
+type SecureBootPolicy struct {
+	AllowUnknown bool // pass if the Secure Boot state cannot be read
+}
+
+func (p *SecureBootPolicy) Check(ctx context.Context) error {
+	f, err := currentFacts(ctx)
+	if err != nil {
+		return fmt.Errorf("policy secure_boot: %w", err)
+	}
+	enabled, ok := f.Bool("secure_boot")
+	if !ok {
+		return unknownFact("secure_boot", "Secure Boot state", p.AllowUnknown)
+	}
+	if !enabled {
+		return fmt.Errorf("policy secure_boot: Secure Boot is disabled")
+	}
+	return nil
+}
+
+// FirmwarePolicy requires UEFI or legacy BIOS boot.
+type FirmwarePolicy struct {
+	Type string // "uefi" or "bios"
+}
+
+func (p *FirmwarePolicy) Check(ctx context.Context) error {
+	f, err := currentFacts(ctx)
+	if err != nil {
+		return fmt.Errorf("policy firmware: %w", err)
+	}
+	uefi, ok := f.Bool("uefi")
+	if !ok {
+		deck.Warningf("Policy firmware: firmware type unknown, skipping check")
+		return nil
+	}
+	current := "bios"
+	if uefi {
+		current = "uefi"
+	}
+	if current != p.Type {
+		return fmt.Errorf("policy firmware: machine booted with %s, want %s", current, p.Type)
+	}
+	return nil
+}
+
+// VirtualMachinePolicy requires the machine to be a VM, optionally on one
+// of AllowedHypervisors, or with Physical set, to not be one.
+type VirtualMachinePolicy struct {
+	Physical           bool
+	AllowedHypervisors []string // "hyperv", "vmware", "kvm", "virtualbox", "xen", ...
+}
+
+func (p *VirtualMachinePolicy) Check(ctx context.Context) error {
+	f, err := currentFacts(ctx)
+	if err != nil {
+		return fmt.Errorf("policy virtual_machine: %w", err)
+	}
+	virtual, ok := f.Bool("virtual")
+	if !ok {
+		deck.Warningf("Policy virtual_machine: hardware vendor unknown, skipping check")
+		return nil
+	}
+	hypervisor := f.String("hypervisor")
+	if p.Physical {
+		if virtual {
+			return fmt.Errorf("policy virtual_machine: machine is a %s virtual machine, want physical", hypervisor)
+		}
+		return nil
+	}
+	if !virtual {
+		return fmt.Errorf("policy virtual_machine: machine is not a virtual machine")
+	}
+	if len(p.AllowedHypervisors) == 0 {
+		return nil
+	}
+	for _, allowed := range p.AllowedHypervisors {
+		if strings.EqualFold(allowed, hypervisor) {
+			return nil
+		}
+	}
+	return fmt.Errorf("policy virtual_machine: hypervisor %q not in allowed list %v", hypervisor, p.AllowedHypervisors)
+}
+
+// requireGB checks the gb key of min_memory and min_disk_size.
+func requireGB(gb int64) error {
+	if gb <= 0 {
+		return fmt.Errorf("requires a positive size in GB, e.g. {gb: 8}")
+	}
+	return nil
+}
+
+var versionRE = regexp.MustCompile(`^\d+(\.\d+)*$`)
+
+func init() {
+	Register("min_memory", func(config interface{}) (Policy, error) {
+		var cfg struct {
+			GB int64 `yaml:"gb"`
+		}
+		if err := decodeShorthand(config, "gb", &cfg); err != nil {
+			return nil, err
+		}
+		if err := requireGB(cfg.GB); err != nil {
+			return nil, err
+		}
+		return &MinMemoryPolicy{GB: cfg.GB}, nil
+	})
+	Register("min_disk_size", func(config interface{}) (Policy, error) {
+		var cfg struct {
+			GB   int64 `yaml:"gb"`
+			Disk *int  `yaml:"disk"`
+		}
+		if err := decodeShorthand(config, "gb", &cfg); err != nil {
+			return nil, err
+		}
+		if err := requireGB(cfg.GB); err != nil {
+			return nil, err
+		}
+		if cfg.Disk != nil && !numberedDisks {
+			return nil, fmt.Errorf("disk is only supported on Windows, where disks are numbered")
+		}
+		if cfg.Disk != nil && *cfg.Disk < 0 {
+			return nil, fmt.Errorf("disk must be a disk number, e.g. 0")
+		}
+		return &MinDiskSizePolicy{GB: cfg.GB, Disk: cfg.Disk}, nil
+	})
+	Register("cpu_arch", func(config interface{}) (Policy, error) {
+		var cfg allowedConfig
+		if err := Decode(config, &cfg); err != nil {
+			return nil, err
+		}
+		if len(cfg.Allowed) == 0 {
+			return nil, fmt.Errorf("requires an allowed list")
+		}
+		return &CPUArchPolicy{AllowedArchs: cfg.Allowed}, nil
+	})
+	Register("tpm", func(config interface{}) (Policy, error) {
+		var cfg struct {
+			MinVersion   string `yaml:"min_version"`
+			AllowUnknown bool   `yaml:"allow_unknown"`
+		}
+		if err := Decode(config, &cfg); err != nil {
+			return nil, err
+		}
+		if cfg.MinVersion != "" && !versionRE.MatchString(cfg.MinVersion) {
+			return nil, fmt.Errorf("min_version must be a version such as \"2.0\", got %q", cfg.MinVersion)
+		}
+		return &TPMPolicy{MinVersion: cfg.MinVersion, AllowUnknown: cfg.AllowUnknown}, nil
+	})
+	Register("secure_boot", func(config interface{}) (Policy, error) {
+		var cfg struct {
+			AllowUnknown bool `yaml:"allow_unknown"`
+		}
+		if err := Decode(config, &cfg); err != nil {
+			return nil, err
+		}
+		return &SecureBootPolicy{AllowUnknown: cfg.AllowUnknown}, nil
+	})
+	Register("firmware", func(config interface{}) (Policy, error) {
+		var t string
+		if err := Decode(config, &t); err != nil {
+			return nil, err
+		}
+		if t = strings.ToLower(t); t != "uefi" && t != "bios" {
+			return nil, fmt.Errorf("must be uefi or bios, got %v", config)
+		}
+		return &FirmwarePolicy{Type: t}, nil
+	})
+	Register("virtual_machine", func(config interface{}) (Policy, error) {
+		if v, ok := config.(bool); ok {
+			return &VirtualMachinePolicy{Physical: !v}, nil
+		}
+		var cfg allowedConfig
+		if err := Decode(config, &cfg); err != nil {
+			return nil, fmt.Errorf("must be true, false or {allowed: [...]}: %w", err)
+		}
+		return &VirtualMachinePolicy{AllowedHypervisors: cfg.Allowed}, nil
+	})
+}
@@ -0,0 +1,196 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mjoliver/glazier-go/internal/facts"
+)
+
+func TestHardwarePolicies(t *testing.T) {
+	orig, origNumbered := currentFacts, numberedDisks
+	defer func() { currentFacts, numberedDisks = orig, origNumbered }()
+	numberedDisks = true
+
+	laptop := facts.Facts{
+		"ram_gb":      16,
+		"disks":       []interface{}{map[string]interface{}{"name": "usb", "size_bytes": int64(32e9)}, map[string]interface{}{"name": "nvme0", "size_bytes": int64(256060514304)}},
+		"arch":        "amd64",
+		"tpm":         true,
+		"tpm_version": "2.0",
+		"secure_boot": true,
+		"uefi":        true,
+		"virtual":     false,
+	}
+	oldVM := facts.Facts{
+		"ram_gb":      4,
+		"disks":       []interface{}{map[string]interface{}{"name": "sda", "size_bytes": 64e9}},
+		"arch":        "386",
+		"tpm":         false,
+		"secure_boot": false,
+		"uefi":        false,
+		"virtual":     true,
+		"hypervisor":  "vmware",
+	}
+	tpm12 := facts.Facts{"tpm": true, "tpm_version": "1.2"}
+	tpmNoVersion := facts.Facts{"tpm": true}
+	twoDisks := facts.Facts{"disks": []interface{}{
+		map[string]interface{}{"name": `\\.\PHYSICALDRIVE0`, "size_bytes": int64(64e9)},
+		map[string]interface{}{"name": `\\.\PHYSICALDRIVE1`, "size_bytes": int64(2e12)},
+	}}
+
+	tests := []struct {
+		name    string
+		facts   facts.Facts
+		policy  string
+		config  interface{}
+		wantErr string
+	}{
+		{"memory", laptop, "min_memory", map[string]interface{}{"gb": 8}, ""},
+		{"memory bare", laptop, "min_memory", 16, ""},
+		{"memory low", oldVM, "min_memory", map[string]interface{}{"gb": 8}, "4 GB of RAM is below the required 8 GB"},
+		{"disk uses largest", laptop, "min_disk_size", map[string]interface{}{"gb": 256}, ""},
+		{"disk small", oldVM, "min_disk_size", map[string]interface{}{"gb": 128}, "largest disk is 64 GB"},
+		{"target disk", twoDisks, "min_disk_size", map[string]interface{}{"gb": 100, "disk": 1}, ""},
+		{"target disk small", twoDisks, "min_disk_size", map[string]interface{}{"gb": 100, "disk": 0}, "disk 0 is 64 GB"},
+		{"target disk missing", twoDisks, "min_disk_size", map[string]interface{}{"gb": 100, "disk": 2}, "disk 2 not found"},
+		{"arch", laptop, "cpu_arch", map[string]interface{}{"allowed": []interface{}{"x64", "arm64"}}, ""},
+		{"arch denied", oldVM, "cpu_arch", map[string]interface{}{"allowed": []interface{}{"amd64"}}, `architecture "386"`},
+		{"tpm", laptop, "tpm", nil, ""},
+		{"tpm version", laptop, "tpm", map[string]interface{}{"min_version": "2.0"}, ""},
+		{"tpm version int", laptop, "tpm", map[string]interface{}{"min_version": 2}, ""},
+		{"tpm missing", oldVM, "tpm", nil, "no TPM found"},
+		{"tpm too old", tpm12, "tpm", map[string]interface{}{"min_version": "2.0"}, `TPM version "1.2" is below the required 2.0`},
+		{"tpm version unknown", tpmNoVersion, "tpm", map[string]interface{}{"min_version": "2.0"}, "TPM version unknown"},
+		{"tpm version unknown allowed", tpmNoVersion, "tpm", map[string]interface{}{"min_version": "2.0", "allow_unknown": true}, ""},
+		{"tpm version not required", tpmNoVersion, "tpm", nil, ""},
+		{"secure boot", laptop, "secure_boot", nil, ""},
+		{"secure boot off", oldVM, "secure_boot", nil, "Secure Boot is disabled"},
+		{"uefi", laptop, "firmware", "uefi", ""},
+		{"uefi on bios", oldVM, "firmware", "UEFI", "booted with bios, want uefi"},
+		{"bios", oldVM, "firmware", "bios", ""},
+		{"vm", oldVM, "virtual_machine", nil, ""},
+		{"vm physical", laptop, "virtual_machine", nil, "not a virtual machine"},
+		{"physical only", laptop, "virtual_machine", false, ""},
+		{"physical only on vm", oldVM, "virtual_machine", false, "vmware virtual machine, want physical"},
+		{"hypervisor", oldVM, "virtual_machine", map[string]interface{}{"allowed": []interface{}{"VMware"}}, ""},
+		{"hypervisor denied", oldVM, "virtual_machine", map[string]interface{}{"allowed": []interface{}{"hyperv"}}, `hypervisor "vmware"`},
+		{"unknown facts pass", facts.Facts{}, "min_memory", map[string]interface{}{"gb": 64}, ""},
+		{"unknown tpm fails", facts.Facts{}, "tpm", nil, "TPM state unknown"},
+		{"unknown tpm allowed", facts.Facts{}, "tpm", map[string]interface{}{"allow_unknown": true}, ""},
+		{"unknown secure boot fails", facts.Facts{}, "secure_boot", nil, "Secure Boot state unknown"},
+		{"unknown secure boot allowed", facts.Facts{}, "secure_boot", map[string]interface{}{"allow_unknown": true}, ""},
+		{"unknown vm passes", facts.Facts{}, "virtual_machine", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			currentFacts = func(context.Context) (facts.Facts, error) { return tt.facts, nil }
+			p, err := NewPolicy(tt.policy, tt.config)
+			if err != nil {
+				t.Fatalf("NewPolicy() error = %v", err)
+			}
+			err = p.Check(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check() error = %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHardwarePolicies_Cancelled(t *testing.T) {
+	orig := currentFacts
+	defer func() { currentFacts = orig }()
+	// Like facts.Current, fail when the collection is cut short.
+	currentFacts = func(ctx context.Context) (facts.Facts, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return facts.Facts{}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, name := range []string{"min_memory", "min_disk_size", "cpu_arch", "tpm", "secure_boot", "firmware", "virtual_machine", "device_model", "chassis_type"} {
+		config := map[string]interface{}{
+			"min_memory":    8,
+			"min_disk_size": 100,
+			"cpu_arch":      map[string]interface{}{"allowed": []interface{}{"amd64"}},
+			"tpm":           map[string]interface{}{"allow_unknown": true},
+			"firmware":      "uefi",
+			"device_model":  map[string]interface{}{"allowed": []interface{}{"Latitude"}},
+			"chassis_type":  map[string]interface{}{"allowed": []interface{}{"laptop"}},
+		}[name]
+		p, err := NewPolicy(name, config)
+		if err != nil {
+			t.Fatalf("NewPolicy(%s) error = %v", name, err)
+		}
+		if err := p.Check(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: Check() after cancel error = %v, want context.Canceled", name, err)
+		}
+	}
+}
+
+func TestHardwarePolicies_Invalid(t *testing.T) {
+	orig := numberedDisks
+	defer func() { numberedDisks = orig }()
+	numberedDisks = true
+
+	tests := []struct {
+		policy string
+		config interface{}
+	}{
+		{"min_memory", nil},
+		{"min_memory", map[string]interface{}{"gb": "8"}},
+		{"min_disk_size", map[string]interface{}{"gb": 0}},
+		{"min_disk_size", map[string]interface{}{"gb": 8, "disk": -1}},
+		{"min_memory", map[string]interface{}{"gb": 8, "disk": 0}},
+		{"cpu_arch", nil},
+		{"tpm", map[string]interface{}{"min_version": true}},
+		{"firmware", nil},
+		{"firmware", "efi"},
+		{"virtual_machine", "yes"},
+	}
+	for _, tt := range tests {
+		if _, err := NewPolicy(tt.policy, tt.config); err == nil {
+			t.Errorf("NewPolicy(%s, %v) expected error", tt.policy, tt.config)
+		}
+	}
+}
+
+func TestMinDiskSize_UnnumberedDisks(t *testing.T) {
+	orig := numberedDisks
+	defer func() { numberedDisks = orig }()
+	numberedDisks = false
+
+	_, err := NewPolicy("min_disk_size", map[string]interface{}{"gb": 100, "disk": 0})
+	if err == nil || !strings.Contains(err.Error(), "only supported on Windows") {
+		t.Errorf("NewPolicy() error = %v, want disk rejected", err)
+	}
+	if _, err := NewPolicy("min_disk_size", map[string]interface{}{"gb": 100}); err != nil {
+		t.Errorf("NewPolicy() without disk error = %v", err)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2.0", "2.0", 0},
+		{"2", "2.0", 0},
+		{"1.2", "2.0", -1},
+		{"2.0", "1.2", 1},
+		{"", "2.0", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
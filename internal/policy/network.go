@@ -0,0 +1,295 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/deck"
+	"github.com/mjoliver/glazier-go/internal/httpclient"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultNetworkTimeout bounds each URL check when no timeout is given.
+const DefaultNetworkTimeout = 10 * time.Second
+
+// lookupHost resolves a host name. Tests replace it to simulate DNS
+// failures without a network.
+var lookupHost = net.DefaultResolver.LookupHost
+
+// proxyFor returns the proxy for a request, or nil to connect directly.
+// Tests replace it, as http.ProxyFromEnvironment reads the environment once.
+var proxyFor = http.ProxyFromEnvironment
+
+// URLResult is the outcome of checking one URL. Stage names the step that
+// failed ("url", "dns", "connect" or "http"), or is "" on success. Proxy is
+// the host of the proxy the request went through, if any; the dns and
+// connect steps are skipped then.
+type URLResult struct {
+	URL     string
+	Proxy   string
+	DNS     time.Duration
+	Connect time.Duration
+	HTTP    time.Duration
+	Status  int
+	Stage   string
+	Err     error
+}
+
+func (r URLResult) String() string {
+	u := httpclient.RedactURL(r.URL)
+	if r.Err != nil {
+		return fmt.Sprintf("%s: %s failed after %s: %v", u, r.Stage, r.elapsed().Round(time.Millisecond), r.Err)
+	}
+	if r.Proxy != "" {
+		return fmt.Sprintf("%s: HTTP %d via proxy %s (http %s)", u, r.Status, r.Proxy, r.HTTP.Round(time.Millisecond))
+	}
+	return fmt.Sprintf("%s: HTTP %d (dns %s, connect %s, http %s)", u, r.Status,
+		r.DNS.Round(time.Millisecond), r.Connect.Round(time.Millisecond), r.HTTP.Round(time.Millisecond))
+}
+
+func (r URLResult) elapsed() time.Duration {
+	return r.DNS + r.Connect + r.HTTP
+}
+
+// CheckURL confirms that rawURL's host resolves, accepts a TCP connection
+// and answers an HTTP GET with an accepted status, all within timeout.
+// With no accepted statuses, any status below 400 passes. When the
+// environment sets a proxy for rawURL, only the GET is made, through it.
+func CheckURL(ctx context.Context, rawURL string, timeout time.Duration, accepted []int) URLResult {
+	r := URLResult{URL: rawURL}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		r.Stage, r.Err = "url", fmt.Errorf("not an http(s) URL")
+		return r
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		r.Stage, r.Err = "url", err
+		return r
+	}
+	proxy, err := proxyFor(req)
+	if err != nil {
+		r.Stage, r.Err = "url", fmt.Errorf("proxy: %w", err)
+		return r
+	}
+	if proxy != nil {
+		r.Proxy = proxy.Host
+		return r.get(req, pinnedClient("", ""), accepted)
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	start := time.Now()
+	addrs := []string{u.Hostname()}
+	if net.ParseIP(u.Hostname()) == nil {
+		addrs, err = lookupHost(ctx, u.Hostname())
+		if err == nil && len(addrs) == 0 {
+			err = fmt.Errorf("no addresses for %s", u.Hostname())
+		}
+	}
+	r.DNS = time.Since(start)
+	if err != nil {
+		r.Stage, r.Err = "dns", err
+		return r
+	}
+
+	start = time.Now()
+	var d net.Dialer
+	var target string
+	for _, addr := range addrs {
+		target = net.JoinHostPort(addr, port)
+		var conn net.Conn
+		if conn, err = d.DialContext(ctx, "tcp", target); err == nil {
+			conn.Close()
+			break
+		}
+	}
+	r.Connect = time.Since(start)
+	if err != nil {
+		r.Stage, r.Err = "connect", err
+		return r
+	}
+
+	return r.get(req, pinnedClient(net.JoinHostPort(u.Hostname(), port), target), accepted)
+}
+
+// get makes the HTTP step of a check and records its outcome in r.
+func (r URLResult) get(req *http.Request, client *http.Client, accepted []int) URLResult {
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		r.Status = resp.StatusCode
+	}
+	r.HTTP = time.Since(start)
+	if err != nil {
+		// *url.Error repeats the URL, which may carry credentials.
+		if ue, ok := err.(*url.Error); ok {
+			err = ue.Err
+		}
+		r.Stage, r.Err = "http", err
+		return r
+	}
+	if !statusAccepted(r.Status, accepted) {
+		r.Stage = "http"
+		if len(accepted) > 0 {
+			r.Err = fmt.Errorf("status %d not in %v", r.Status, accepted)
+		} else {
+			r.Err = fmt.Errorf("status %d", r.Status)
+		}
+	}
+	return r
+}
+
+// pinnedClient returns a client with the configured credential rules that
+// connects to target for the checked URL's host:port, whatever it resolves
+// to, so the HTTP check uses the address that was just tested. Redirects to
+// any other host or port are dialled normally, and an empty addr pins
+// nothing. Non-ASCII host names reach the dialer in punycode, so they are
+// not pinned. TLS still verifies the host name. The caller's context bounds
+// the request.
+func pinnedClient(addr, target string) *http.Client {
+	client := httpclient.New(0)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableKeepAlives = true
+	transport.Proxy = proxyFor
+	addr = canonicalAddr(addr)
+	transport.DialContext = func(ctx context.Context, network, a string) (net.Conn, error) {
+		if addr != "" && canonicalAddr(a) == addr {
+			a = target
+		}
+		var d net.Dialer
+		return d.DialContext(ctx, network, a)
+	}
+	client.Transport.(*httpclient.AuthTransport).Base = transport
+	return client
+}
+
+// canonicalAddr lowercases the host of host:port, so the address the
+// transport dials matches however the URL spelled the host name. Callers
+// make the port explicit.
+func canonicalAddr(hostport string) string {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return strings.ToLower(hostport)
+	}
+	return net.JoinHostPort(strings.ToLower(host), port)
+}
+
+func statusAccepted(status int, accepted []int) bool {
+	if len(accepted) == 0 {
+		return status < 400
+	}
+	for _, s := range accepted {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyURLs checks every URL concurrently and logs each result. It returns
+// an error listing the URLs that failed.
+func VerifyURLs(ctx context.Context, urls []string, timeout time.Duration, accepted []int) ([]URLResult, error) {
+	results := make([]URLResult, len(urls))
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			results[i] = CheckURL(ctx, u, timeout, accepted)
+		}(i, u)
+	}
+	wg.Wait()
+
+	var failed []string
+	for _, r := range results {
+		if r.Err != nil {
+			deck.Warningf("Network check: %s", r)
+			failed = append(failed, r.String())
+		} else {
+			deck.Infof("Network check: %s", r)
+		}
+	}
+	if len(failed) > 0 {
+		return results, fmt.Errorf("%d of %d URLs unreachable: %s", len(failed), len(urls), strings.Join(failed, "; "))
+	}
+	return results, nil
+}
+
+// NetworkPolicy requires every URL to be reachable.
+type NetworkPolicy struct {
+	URLs     []string
+	Timeout  time.Duration
+	Accepted []int // accepted HTTP statuses; empty means any below 400
+}
+
+func (p *NetworkPolicy) Check(ctx context.Context) error {
+	if _, err := VerifyURLs(ctx, p.URLs, p.Timeout, p.Accepted); err != nil {
+		return fmt.Errorf("policy network: %w", err)
+	}
+	return nil
+}
+
+// statusList is a single HTTP status code or a list of them.
+type statusList []int
+
+func (l *statusList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var s int
+		if err := node.Decode(&s); err != nil {
+			return err
+		}
+		*l = statusList{s}
+		return nil
+	}
+	var list []int
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// newNetworkPolicy builds a network policy from either a list of URLs or a
+// map with urls, timeout and status keys.
+func newNetworkPolicy(config interface{}) (Policy, error) {
+	cfg := struct {
+		URLs    StringList `yaml:"urls"`
+		Timeout Duration   `yaml:"timeout"`
+		Status  statusList `yaml:"status"`
+	}{Timeout: Duration(DefaultNetworkTimeout)}
+	if err := decodeShorthand(config, "urls", &cfg); err != nil {
+		return nil, err
+	}
+	if len(cfg.URLs) == 0 {
+		return nil, fmt.Errorf("requires a list of urls")
+	}
+	for _, u := range cfg.URLs {
+		if parsed, err := url.Parse(u); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("%q is not an http(s) URL", httpclient.RedactURL(u))
+		}
+	}
+	if cfg.Timeout <= 0 {
+		return nil, fmt.Errorf("timeout must be positive")
+	}
+	return &NetworkPolicy{URLs: cfg.URLs, Timeout: time.Duration(cfg.Timeout), Accepted: cfg.Status}, nil
+}
+
+func init() {
+	Register("network", newNetworkPolicy)
+}
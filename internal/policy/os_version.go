@@ -0,0 +1,242 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// getBuildNumber returns the OS build number, e.g. 22631.
+var getBuildNumber = func() int {
+	_, _, build := getOSVersion()
+	return build
+}
+
+// getEdition returns the Windows EditionID, e.g. "Professional" or
+// "ServerStandard", or "" if unknown.
+var getEdition = detectEdition
+
+// getRelease returns the Windows feature release, e.g. "23H2", or "" if
+// unknown.
+var getRelease = detectRelease
+
+// versionRange is a space-separated list of comparisons that must all hold,
+// e.g. ">=22621 <26100". A bare value means equality.
+type versionRange []comparison
+
+type comparison struct {
+	op    string
+	value int
+	text  string
+}
+
+var comparisonRE = regexp.MustCompile(`^(>=|<=|==|!=|>|<|=)?(.+)$`)
+
+// parseRange parses a range expression, converting each operand with parse.
+func parseRange(expr string, parse func(string) (int, error)) (versionRange, error) {
+	fields := strings.Fields(expr)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty range")
+	}
+	var r versionRange
+	for _, f := range fields {
+		m := comparisonRE.FindStringSubmatch(f)
+		v, err := parse(m[2])
+		if err != nil {
+			return nil, fmt.Errorf("range %q: %w", expr, err)
+		}
+		op := m[1]
+		if op == "" || op == "==" {
+			op = "="
+		}
+		r = append(r, comparison{op: op, value: v, text: f})
+	}
+	return r, nil
+}
+
+// contains reports whether v satisfies every comparison.
+func (r versionRange) contains(v int) bool {
+	for _, c := range r {
+		var ok bool
+		switch c.op {
+		case "=":
+			ok = v == c.value
+		case "!=":
+			ok = v != c.value
+		case ">":
+			ok = v > c.value
+		case ">=":
+			ok = v >= c.value
+		case "<":
+			ok = v < c.value
+		case "<=":
+			ok = v <= c.value
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (r versionRange) String() string {
+	parts := make([]string, len(r))
+	for i, c := range r {
+		parts[i] = c.text
+	}
+	return strings.Join(parts, " ")
+}
+
+func parseBuild(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid build number %q", s)
+	}
+	return n, nil
+}
+
+var (
+	releaseRE   = regexp.MustCompile(`^(\d\d)[Hh]([12])$`)
+	releaseIDRE = regexp.MustCompile(`^(\d\d)(\d\d)$`)
+)
+
+// parseRelease orders feature release names. Names are "YYHn" ("23H2"),
+// or the "YYMM" release IDs used up to 2004, which count as the half of
+// the year they shipped in.
+func parseRelease(s string) (int, error) {
+	if m := releaseRE.FindStringSubmatch(s); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		half, _ := strconv.Atoi(m[2])
+		return year*10 + half, nil
+	}
+	if m := releaseIDRE.FindStringSubmatch(s); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		if month >= 1 && month <= 12 {
+			half := 1
+			if month > 6 {
+				half = 2
+			}
+			return year*10 + half, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid release %q, want e.g. 23H2", s)
+}
+
+// editionAliases maps the names used on product boxes to EditionIDs.
+var editionAliases = map[string]string{
+	"home":               "core",
+	"pro":                "professional",
+	"proeducation":       "professionaleducation",
+	"proforworkstations": "professionalworkstation",
+	"standard":           "serverstandard",
+	"datacenter":         "serverdatacenter",
+}
+
+// normalizeEdition lowercases an edition name, drops spaces and resolves
+// aliases, so "Pro" and "Professional" compare equal.
+func normalizeEdition(s string) string {
+	s = strings.ToLower(strings.ReplaceAll(s, " ", ""))
+	if alias, ok := editionAliases[s]; ok {
+		return alias
+	}
+	return s
+}
+
+// checkOSBuild applies the build, edition and release constraints of p.
+func (p *OSVersionPolicy) checkOSBuild() error {
+	if p.MinBuild > 0 || p.MaxBuild > 0 || p.Build != nil {
+		build := getBuildNumber()
+		if p.MinBuild > 0 && build < p.MinBuild {
+			return fmt.Errorf("policy os_version: build %d is below min_build %d", build, p.MinBuild)
+		}
+		if p.MaxBuild > 0 && build > p.MaxBuild {
+			return fmt.Errorf("policy os_version: build %d is above max_build %d", build, p.MaxBuild)
+		}
+		if p.Build != nil && !p.Build.contains(build) {
+			return fmt.Errorf("policy os_version: build %d not in range %q", build, p.Build)
+		}
+	}
+
+	if len(p.Editions) > 0 {
+		edition := getEdition()
+		matched := false
+		for _, e := range p.Editions {
+			if normalizeEdition(e) == normalizeEdition(edition) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("policy os_version: edition %q not in allowed list %v", edition, p.Editions)
+		}
+	}
+
+	if len(p.Releases) > 0 {
+		current := getRelease()
+		release, err := parseRelease(current)
+		if err != nil {
+			return fmt.Errorf("policy os_version: cannot determine feature release: %w", err)
+		}
+		matched := false
+		for _, r := range p.Releases {
+			if r.contains(release) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("policy os_version: release %q not in allowed list %v", current, p.Releases)
+		}
+	}
+	return nil
+}
+
+// osVersionConfig is the YAML config of os_version.
+type osVersionConfig struct {
+	OS              *string    `yaml:"os"`
+	Version         string     `yaml:"version"`
+	AllowedVersions []string   `yaml:"allowed_versions"`
+	MinBuild        int        `yaml:"min_build"`
+	MaxBuild        int        `yaml:"max_build"`
+	Build           *string    `yaml:"build"`
+	Edition         StringList `yaml:"edition"`
+	Release         StringList `yaml:"release"`
+}
+
+// newOSVersionPolicy builds an os_version policy from its YAML config.
+func newOSVersionPolicy(config interface{}) (Policy, error) {
+	var cfg osVersionConfig
+	if err := Decode(config, &cfg); err != nil {
+		return nil, err
+	}
+	p := &OSVersionPolicy{
+		OS:       "windows",
+		MinBuild: cfg.MinBuild,
+		MaxBuild: cfg.MaxBuild,
+		Editions: cfg.Edition,
+	}
+	if cfg.OS != nil {
+		p.OS = *cfg.OS
+	}
+	if cfg.Version != "" {
+		p.AllowedVersions = []string{cfg.Version}
+	}
+	p.AllowedVersions = append(p.AllowedVersions, cfg.AllowedVersions...)
+	if cfg.Build != nil {
+		r, err := parseRange(*cfg.Build, parseBuild)
+		if err != nil {
+			return nil, fmt.Errorf("build: %w", err)
+		}
+		p.Build = r
+	}
+	for _, expr := range cfg.Release {
+		r, err := parseRange(expr, parseRelease)
+		if err != nil {
+			return nil, fmt.Errorf("release: %w", err)
+		}
+		p.Releases = append(p.Releases, r)
+	}
+	return p, nil
+}
@@ -1,6 +1,7 @@
 package policy
 
 import (
+	"context"
 	"testing"
 )
 
@@ -65,7 +66,7 @@ func TestOSVersion_Policy(t *testing.T) {
 			// To be safe on Linux dev env:
 			p.OS = ""
 
-			err := p.Check()
+			err := p.Check(context.Background())
 			if tc.shouldPass && err != nil {
 				t.Errorf("Expected pass, got error: %v", err)
 			}
@@ -75,3 +76,73 @@ func TestOSVersion_Policy(t *testing.T) {
 		})
 	}
 }
+
+func TestOSVersion_BuildEditionRelease(t *testing.T) {
+	origBuild, origEdition, origRelease := getBuildNumber, getEdition, getRelease
+	defer func() { getBuildNumber, getEdition, getRelease = origBuild, origEdition, origRelease }()
+	getBuildNumber = func() int { return 22631 }
+	getEdition = func() string { return "Professional" }
+	getRelease = func() string { return "23H2" }
+
+	tests := []struct {
+		name       string
+		config     map[string]interface{}
+		shouldPass bool
+	}{
+		{"min_build", map[string]interface{}{"min_build": 22621}, true},
+		{"min_build too high", map[string]interface{}{"min_build": 26100}, false},
+		{"max_build", map[string]interface{}{"max_build": 22000}, false},
+		{"range", map[string]interface{}{"build": ">=22621 <26100"}, true},
+		{"range excludes", map[string]interface{}{"build": ">=26100"}, false},
+		{"exact build", map[string]interface{}{"build": 22631}, true},
+		{"not equal", map[string]interface{}{"build": "!=22631"}, false},
+		{"edition alias", map[string]interface{}{"edition": "Pro"}, true},
+		{"edition list", map[string]interface{}{"edition": []interface{}{"Enterprise", "Education"}}, false},
+		{"release", map[string]interface{}{"release": "23H2"}, true},
+		{"release range", map[string]interface{}{"release": ">=24H2"}, false},
+		{"release list", map[string]interface{}{"release": []interface{}{"22H2", ">=23h2 <25H1"}}, true},
+		{"release after release id", map[string]interface{}{"release": ">2004"}, true},
+		{"combined", map[string]interface{}{"min_build": 22621, "edition": "Professional", "release": "23H2"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["os"] = ""
+			p, err := NewPolicy("os_version", tt.config)
+			if err != nil {
+				t.Fatalf("NewPolicy() error = %v", err)
+			}
+			err = p.Check(context.Background())
+			if tt.shouldPass && err != nil {
+				t.Errorf("Expected pass, got error: %v", err)
+			}
+			if !tt.shouldPass && err == nil {
+				t.Errorf("Expected failure, got nil")
+			}
+		})
+	}
+}
+
+func TestOSVersion_InvalidConfig(t *testing.T) {
+	for _, config := range []map[string]interface{}{
+		{"build": ">=abc"},
+		{"build": ""},
+		{"min_build": "22621"},
+		{"release": "24H3"},
+		{"release": ">=2024"},
+	} {
+		if _, err := NewPolicy("os_version", config); err == nil {
+			t.Errorf("NewPolicy(%v) expected error", config)
+		}
+	}
+}
+
+func TestOSVersion_UnknownRelease(t *testing.T) {
+	orig := getRelease
+	defer func() { getRelease = orig }()
+	getRelease = func() string { return "" }
+
+	p := &OSVersionPolicy{Releases: []versionRange{{{op: ">=", value: 232, text: ">=23H2"}}}}
+	if err := p.Check(context.Background()); err == nil {
+		t.Error("Expected failure when the release is unknown")
+	}
+}
@@ -1,26 +1,30 @@
 package policy
 
 import (
+	"context"
 	"fmt"
 	"runtime"
 	"strings"
-)
 
-// Policy represents a system requirement check.
-type Policy interface {
-	// Check evaluates the policy and returns an error if it fails.
-	Check() error
-}
+	"github.com/google/deck"
+	"github.com/mjoliver/glazier-go/internal/facts"
+)
 
 // OSVersionPolicy checks if the OS and version match expected criteria.
 // Uses exact version matching to prevent configs meant for one OS version
 // from accidentally running on a different version (e.g. Server 2019 vs 2022).
+// Build, edition and feature release constraints narrow it further.
 type OSVersionPolicy struct {
 	OS              string   // "windows", "linux", etc.
 	AllowedVersions []string // e.g. ["10", "11"] or ["2019", "2022"]
+
+	MinBuild, MaxBuild int            // inclusive build bounds, 0 for none
+	Build              versionRange   // e.g. ">=22621 <26100"
+	Editions           []string       // e.g. ["Pro", "Enterprise"]
+	Releases           []versionRange // any may match, e.g. ["23H2", ">=24H2"]
 }
 
-func (p *OSVersionPolicy) Check() error {
+func (p *OSVersionPolicy) Check(ctx context.Context) error {
 	// Check OS type
 	if p.OS != "" && !strings.EqualFold(runtime.GOOS, p.OS) {
 		return fmt.Errorf("policy os_version: expected OS %q, got %q", p.OS, runtime.GOOS)
@@ -29,17 +33,19 @@ func (p *OSVersionPolicy) Check() error {
 	// Check version if specified
 	if len(p.AllowedVersions) > 0 {
 		current := getCurrentWindowsVersion()
-
+		matched := false
 		for _, allowed := range p.AllowedVersions {
 			if strings.EqualFold(current, allowed) {
-				return nil
+				matched = true
+				break
 			}
 		}
-
-		return fmt.Errorf("policy os_version: version %q not in allowed list %v", current, p.AllowedVersions)
+		if !matched {
+			return fmt.Errorf("policy os_version: version %q not in allowed list %v", current, p.AllowedVersions)
+		}
 	}
 
-	return nil
+	return p.checkOSBuild()
 }
 
 // currentWindowsVersion returns the user-facing Windows version string.
@@ -65,17 +71,47 @@ func WindowsVersion() string {
 	return fmt.Sprintf("Windows %s (build %d)", ver, build)
 }
 
+// currentFacts returns the machine facts shared with config templates. If
+// they cannot be collected, every fact is unknown; it only fails when ctx
+// ends first, so that a cancelled check is not mistaken for unknown facts.
+var currentFacts = func(ctx context.Context) (facts.Facts, error) {
+	f, err := facts.Current(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		deck.Warningf("policy: %v", err)
+		return facts.Facts{}, nil
+	}
+	return f, nil
+}
+
+// getDeviceModel returns the device model, or "" if unknown.
+func getDeviceModel(ctx context.Context) (string, error) {
+	f, err := currentFacts(ctx)
+	return f.String("model"), err
+}
+
+// getChassisType returns the chassis type ("desktop", "laptop", ...), or "" if unknown.
+func getChassisType(ctx context.Context) (string, error) {
+	f, err := currentFacts(ctx)
+	return f.String("chassis"), err
+}
+
 // DeviceModelPolicy checks if the device model matches allowed models.
 type DeviceModelPolicy struct {
 	AllowedModels []string
 }
 
-func (p *DeviceModelPolicy) Check() error {
+func (p *DeviceModelPolicy) Check(ctx context.Context) error {
 	if len(p.AllowedModels) == 0 {
 		return nil
 	}
 
-	model := getDeviceModel()
+	model, err := getDeviceModel(ctx)
+	if err != nil {
+		return fmt.Errorf("policy device_model: %w", err)
+	}
 	if model == "" {
 		return nil
 	}
@@ -94,12 +130,15 @@ type ChassisTypePolicy struct {
 	AllowedTypes []string // "desktop", "laptop", "tablet", "server"
 }
 
-func (p *ChassisTypePolicy) Check() error {
+func (p *ChassisTypePolicy) Check(ctx context.Context) error {
 	if len(p.AllowedTypes) == 0 {
 		return nil
 	}
 
-	chassis := getChassisType()
+	chassis, err := getChassisType(ctx)
+	if err != nil {
+		return fmt.Errorf("policy chassis_type: %w", err)
+	}
 	if chassis == "" {
 		return nil
 	}
@@ -113,52 +152,29 @@ func (p *ChassisTypePolicy) Check() error {
 	return fmt.Errorf("policy chassis_type: type %q not in allowed list %v", chassis, p.AllowedTypes)
 }
 
-// NewPolicy creates a policy from a YAML config.
-func NewPolicy(policyName string, config interface{}) (Policy, error) {
-	switch policyName {
-	case "os_version":
-		p := &OSVersionPolicy{OS: "windows"}
-		if m, ok := config.(map[string]interface{}); ok {
-			if v, ok := m["os"].(string); ok {
-				p.OS = v
-			}
-			if v, ok := m["version"].(string); ok {
-				p.AllowedVersions = []string{v}
-			}
-			if v, ok := m["allowed_versions"].([]interface{}); ok {
-				for _, item := range v {
-					if s, ok := item.(string); ok {
-						p.AllowedVersions = append(p.AllowedVersions, s)
-					}
-				}
-			}
-		}
-		return p, nil
-	case "device_model":
-		p := &DeviceModelPolicy{}
-		if m, ok := config.(map[string]interface{}); ok {
-			if v, ok := m["allowed"].([]interface{}); ok {
-				for _, item := range v {
-					if s, ok := item.(string); ok {
-						p.AllowedModels = append(p.AllowedModels, s)
-					}
-				}
-			}
-		}
-		return p, nil
-	case "chassis_type":
-		p := &ChassisTypePolicy{}
-		if m, ok := config.(map[string]interface{}); ok {
-			if v, ok := m["allowed"].([]interface{}); ok {
-				for _, item := range v {
-					if s, ok := item.(string); ok {
-						p.AllowedTypes = append(p.AllowedTypes, s)
-					}
-				}
-			}
-		}
-		return p, nil
-	default:
-		return nil, fmt.Errorf("unknown policy: %s", policyName)
+// allowedConfig is the config of policies that match against a list.
+type allowedConfig struct {
+	Allowed []string `yaml:"allowed"`
+}
+
+func newDeviceModelPolicy(config interface{}) (Policy, error) {
+	var cfg allowedConfig
+	if err := Decode(config, &cfg); err != nil {
+		return nil, err
+	}
+	return &DeviceModelPolicy{AllowedModels: cfg.Allowed}, nil
+}
+
+func newChassisTypePolicy(config interface{}) (Policy, error) {
+	var cfg allowedConfig
+	if err := Decode(config, &cfg); err != nil {
+		return nil, err
 	}
+	return &ChassisTypePolicy{AllowedTypes: cfg.Allowed}, nil
+}
+
+func init() {
+	Register("os_version", newOSVersionPolicy)
+	Register("device_model", newDeviceModelPolicy)
+	Register("chassis_type", newChassisTypePolicy)
 }
@@ -46,10 +46,12 @@ func detectWindowsVersion() string {
 	return runtime.GOOS
 }
 
-func getDeviceModel() string {
+// detectEdition is a no-op on non-Windows.
+func detectEdition() string {
 	return ""
 }
 
-func getChassisType() string {
+// detectRelease is a no-op on non-Windows.
+func detectRelease() string {
 	return ""
 }
@@ -1,8 +1,13 @@
 package policy
 
 import (
+	"context"
+	"errors"
 	"runtime"
+	"strings"
 	"testing"
+
+	"github.com/mjoliver/glazier-go/internal/facts"
 )
 
 func TestOSVersionPolicy_Check(t *testing.T) {
@@ -18,7 +23,7 @@ func TestOSVersionPolicy_Check(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := tt.policy.Check()
+			err := tt.policy.Check(context.Background())
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -32,19 +37,19 @@ func TestOSVersionPolicy_ExactVersionMatch(t *testing.T) {
 
 	// Should pass with current version in list
 	p := &OSVersionPolicy{AllowedVersions: []string{current}}
-	if err := p.Check(); err != nil {
+	if err := p.Check(context.Background()); err != nil {
 		t.Errorf("should pass with matching version, got: %v", err)
 	}
 
 	// Should pass with current version among many
 	p = &OSVersionPolicy{AllowedVersions: []string{"99", current, "98"}}
-	if err := p.Check(); err != nil {
+	if err := p.Check(context.Background()); err != nil {
 		t.Errorf("should pass when current is in list, got: %v", err)
 	}
 
 	// Should fail with non-matching version
 	p = &OSVersionPolicy{AllowedVersions: []string{"NonExistentVersion"}}
-	if err := p.Check(); err == nil {
+	if err := p.Check(context.Background()); err == nil {
 		t.Error("should fail with non-matching version")
 	}
 }
@@ -60,7 +65,7 @@ func TestOSVersionPolicy_ServerVersions(t *testing.T) {
 	// Verify that a Server 2019 policy does NOT match a Windows 11 host
 	if current == "11" {
 		p := &OSVersionPolicy{AllowedVersions: []string{"Server 2019"}}
-		if err := p.Check(); err == nil {
+		if err := p.Check(context.Background()); err == nil {
 			t.Error("Windows 11 should NOT match Server 2019 policy")
 		}
 	}
@@ -68,7 +73,7 @@ func TestOSVersionPolicy_ServerVersions(t *testing.T) {
 	// Verify that a Windows 10 policy does NOT match a Windows 11 host
 	if current == "11" {
 		p := &OSVersionPolicy{AllowedVersions: []string{"10"}}
-		if err := p.Check(); err == nil {
+		if err := p.Check(context.Background()); err == nil {
 			t.Error("Windows 11 should NOT match Windows 10 policy")
 		}
 	}
@@ -81,21 +86,21 @@ func TestWindowsVersion(t *testing.T) {
 
 func TestDeviceModelPolicy_Check(t *testing.T) {
 	p := &DeviceModelPolicy{}
-	if err := p.Check(); err != nil {
+	if err := p.Check(context.Background()); err != nil {
 		t.Errorf("empty list should pass, got: %v", err)
 	}
 
-	model := getDeviceModel()
+	model, _ := getDeviceModel(context.Background())
 	t.Logf("Detected model: %q", model)
 
 	if model != "" {
 		p = &DeviceModelPolicy{AllowedModels: []string{model}}
-		if err := p.Check(); err != nil {
+		if err := p.Check(context.Background()); err != nil {
 			t.Errorf("matching model should pass, got: %v", err)
 		}
 
 		p = &DeviceModelPolicy{AllowedModels: []string{"NonExistent12345"}}
-		if err := p.Check(); err == nil {
+		if err := p.Check(context.Background()); err == nil {
 			t.Error("non-matching model should fail")
 		}
 	}
@@ -103,21 +108,42 @@ func TestDeviceModelPolicy_Check(t *testing.T) {
 
 func TestChassisTypePolicy_Check(t *testing.T) {
 	p := &ChassisTypePolicy{}
-	if err := p.Check(); err != nil {
+	if err := p.Check(context.Background()); err != nil {
 		t.Errorf("empty list should pass, got: %v", err)
 	}
 
-	chassis := getChassisType()
+	chassis, _ := getChassisType(context.Background())
 	t.Logf("Detected chassis: %q", chassis)
 
 	if chassis != "" {
 		p = &ChassisTypePolicy{AllowedTypes: []string{chassis}}
-		if err := p.Check(); err != nil {
+		if err := p.Check(context.Background()); err != nil {
 			t.Errorf("matching chassis should pass, got: %v", err)
 		}
 	}
 }
 
+func TestHardwarePolicies_Facts(t *testing.T) {
+	orig := currentFacts
+	defer func() { currentFacts = orig }()
+	currentFacts = func(context.Context) (facts.Facts, error) {
+		return facts.Facts{"model": "Latitude 7440", "chassis": "laptop"}, nil
+	}
+
+	if err := (&DeviceModelPolicy{AllowedModels: []string{"latitude 7440"}}).Check(context.Background()); err != nil {
+		t.Errorf("matching model should pass, got: %v", err)
+	}
+	if err := (&DeviceModelPolicy{AllowedModels: []string{"OptiPlex 7010"}}).Check(context.Background()); err == nil {
+		t.Error("non-matching model should fail")
+	}
+	if err := (&ChassisTypePolicy{AllowedTypes: []string{"laptop"}}).Check(context.Background()); err != nil {
+		t.Errorf("matching chassis should pass, got: %v", err)
+	}
+	if err := (&ChassisTypePolicy{AllowedTypes: []string{"desktop"}}).Check(context.Background()); err == nil {
+		t.Error("non-matching chassis should fail")
+	}
+}
+
 func TestNewPolicy_WithConfig(t *testing.T) {
 	// Single version
 	p, err := NewPolicy("os_version", map[string]interface{}{
@@ -127,7 +153,7 @@ func TestNewPolicy_WithConfig(t *testing.T) {
 	if err != nil {
 		t.Fatalf("NewPolicy() error = %v", err)
 	}
-	if err := p.Check(); err != nil {
+	if err := p.Check(context.Background()); err != nil {
 		t.Errorf("should pass, got: %v", err)
 	}
 
@@ -145,3 +171,99 @@ func TestNewPolicy_WithConfig(t *testing.T) {
 		t.Error("should fail for unknown policy")
 	}
 }
+
+func TestCombinatorPolicies(t *testing.T) {
+	orig := currentFacts
+	defer func() { currentFacts = orig }()
+	currentFacts = func(context.Context) (facts.Facts, error) {
+		return facts.Facts{"model": "Surface Pro 9", "chassis": "tablet"}, nil
+	}
+
+	laptop := map[string]interface{}{"chassis_type": map[string]interface{}{"allowed": []interface{}{"laptop"}}}
+	surface := map[string]interface{}{"device_model": map[string]interface{}{"allowed": []interface{}{"Surface"}}}
+	tests := []struct {
+		name    string
+		policy  string
+		config  interface{}
+		wantErr string
+	}{
+		{"any_of matches second", "any_of", []interface{}{laptop, surface}, ""},
+		{"any_of none", "any_of", []interface{}{laptop}, `no branch matched: [0] chassis_type: policy chassis_type: type "tablet"`},
+		{"all_of", "all_of", []interface{}{surface}, ""},
+		{"all_of fails", "all_of", []interface{}{surface, laptop}, "branch 1 (chassis_type) failed"},
+		{"not passes", "not", laptop, ""},
+		{"not fails", "not", surface, "policy not: device_model passed"},
+		{"nested", "not", map[string]interface{}{"any_of": []interface{}{laptop, map[string]interface{}{"not": surface}}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPolicy(tt.policy, tt.config)
+			if err != nil {
+				t.Fatalf("NewPolicy() error = %v", err)
+			}
+			err = p.Check(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check() error = %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNotPolicy_Unchecked(t *testing.T) {
+	orig := currentFacts
+	defer func() { currentFacts = orig }()
+	currentFacts = func(context.Context) (facts.Facts, error) { return facts.Facts{}, nil }
+
+	p, err := NewPolicy("not", "tpm")
+	if err != nil {
+		t.Fatalf("NewPolicy() error = %v", err)
+	}
+	err = p.Check(context.Background())
+	var unknown *UnknownFactError
+	if !errors.As(err, &unknown) || !strings.Contains(err.Error(), "policy not: tpm could not be checked") {
+		t.Errorf("Check() with an unknown TPM error = %v, want an unknown fact failure", err)
+	}
+
+	p, err = NewPolicy("not", map[string]interface{}{"ac_power": map[string]interface{}{"wait": "1h"}})
+	if err != nil {
+		t.Fatalf("NewPolicy() error = %v", err)
+	}
+	getOrig := getPowerStatus
+	defer func() { getPowerStatus = getOrig }()
+	getPowerStatus = func() (powerStatus, error) {
+		return powerStatus{HasBattery: true, BatteryPercent: 50}, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.Check(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Check() after cancel error = %v, want context.Canceled", err)
+	}
+}
+
+func TestCombinatorPolicies_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		config  interface{}
+		wantErr string
+	}{
+		{"empty any_of", "any_of", nil, "any_of requires a non-empty list"},
+		{"all_of not a list", "all_of", map[string]interface{}{"os_version": nil}, "all_of requires a non-empty list"},
+		{"empty not", "not", nil, "not requires a policy"},
+		{"unknown nested", "any_of", []interface{}{"os_version", "chasis_type"}, "any_of[1]: unknown policy: chasis_type"},
+		{"deep", "not", map[string]interface{}{"all_of": []interface{}{map[string]interface{}{"any_of": []interface{}{"nope"}}}}, "not: all_of[0]: any_of[0]: unknown policy: nope"},
+		{"two keys", "any_of", []interface{}{map[string]interface{}{"os_version": nil, "chassis_type": nil}}, "exactly one key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPolicy(tt.policy, tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewPolicy() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
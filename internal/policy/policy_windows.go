@@ -3,11 +3,11 @@
 package policy
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/StackExchange/wmi"
 	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
 )
 
 // getOSVersion returns Windows major, minor, and build number.
@@ -21,14 +21,6 @@ type win32OperatingSystem struct {
 	Caption string
 }
 
-type win32ComputerSystem struct {
-	Model string
-}
-
-type win32SystemEnclosure struct {
-	ChassisTypes []int32
-}
-
 // detectWindowsVersion returns a user-facing version string: "11", "10",
 // "Server 2022", "Server 2019", etc.
 func detectWindowsVersion() string {
@@ -65,46 +57,31 @@ func detectWindowsVersion() string {
 	return "10"
 }
 
-// getDeviceModel returns the device model string via WMI.
-func getDeviceModel() string {
-	var results []win32ComputerSystem
-	if err := wmi.Query("SELECT Model FROM Win32_ComputerSystem", &results); err != nil {
+// currentVersionValue reads a string value from the CurrentVersion key.
+func currentVersionValue(name string) string {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows NT\CurrentVersion`, registry.QUERY_VALUE)
+	if err != nil {
 		return ""
 	}
-	if len(results) > 0 {
-		return strings.TrimSpace(results[0].Model)
+	defer k.Close()
+	v, _, err := k.GetStringValue(name)
+	if err != nil {
+		return ""
 	}
-	return ""
+	return v
 }
 
-// getChassisType returns a human-readable chassis type via WMI.
-func getChassisType() string {
-	var results []win32SystemEnclosure
-	if err := wmi.Query("SELECT ChassisTypes FROM Win32_SystemEnclosure", &results); err != nil {
-		return ""
-	}
-	if len(results) == 0 || len(results[0].ChassisTypes) == 0 {
-		return ""
-	}
+// detectEdition returns the EditionID, e.g. "Professional", "Enterprise"
+// or "ServerDatacenter".
+func detectEdition() string {
+	return currentVersionValue("EditionID")
+}
 
-	switch results[0].ChassisTypes[0] {
-	case 1:
-		return "other"
-	case 2:
-		return "unknown"
-	case 3, 4, 5, 6, 7:
-		return "desktop"
-	case 8, 9, 10, 11, 12, 14, 18, 21:
-		return "laptop"
-	case 13:
-		return "allinone"
-	case 15, 16:
-		return "server"
-	case 17:
-		return "docking"
-	case 30, 31, 32:
-		return "tablet"
-	default:
-		return fmt.Sprintf("type_%d", results[0].ChassisTypes[0])
+// detectRelease returns the feature release, e.g. "23H2". Releases before
+// 20H2 only record a ReleaseId such as "2004".
+func detectRelease() string {
+	if v := currentVersionValue("DisplayVersion"); v != "" {
+		return v
 	}
+	return currentVersionValue("ReleaseId")
 }
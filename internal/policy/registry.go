@@ -0,0 +1,136 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy represents a system requirement check.
+type Policy interface {
+	// Check evaluates the policy and returns an error if it fails. Policies
+	// that wait or use the network stop when ctx is cancelled.
+	Check(ctx context.Context) error
+}
+
+// Factory creates a Policy from its raw YAML config: nil for a bare policy
+// name, otherwise whatever follows the name (a map, list or scalar).
+type Factory func(config interface{}) (Policy, error)
+
+var Registry = map[string]Factory{}
+
+// Register adds a policy factory to the global registry.
+func Register(name string, factory Factory) {
+	if _, exists := Registry[name]; exists {
+		panic(fmt.Sprintf("Policy %s already registered", name))
+	}
+	Registry[name] = factory
+}
+
+// NewPolicy creates a policy from a YAML config.
+func NewPolicy(policyName string, config interface{}) (Policy, error) {
+	factory, ok := Registry[policyName]
+	if !ok {
+		return nil, fmt.Errorf("unknown policy: %s", policyName)
+	}
+	return factory(config)
+}
+
+// yamlLineRE matches the line prefix of yaml errors, which refers to the
+// re-encoded config rather than the config file.
+var yamlLineRE = regexp.MustCompile(`^line \d+: `)
+
+// Decode decodes a raw YAML config into out, typically a pointer to a
+// config struct. Keys that do not match a field of out are an error, so
+// typos are caught by -validate. A nil config leaves out unchanged.
+func Decode(config interface{}, out interface{}) error {
+	if config == nil {
+		return nil
+	}
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err = dec.Decode(out)
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		msgs := make([]string, len(typeErr.Errors))
+		for i, e := range typeErr.Errors {
+			e = yamlLineRE.ReplaceAllString(e, "")
+			if i := strings.Index(e, " in type "); i >= 0 {
+				e = e[:i]
+			}
+			msgs[i] = e
+		}
+		return errors.New(strings.Join(msgs, "; "))
+	}
+	return err
+}
+
+// Duration is a config duration, written like "90s" or "10m", or as a
+// number of seconds.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	var v time.Duration
+	if node.Tag == "!!int" {
+		var secs int64
+		if err := node.Decode(&secs); err != nil {
+			return err
+		}
+		v = time.Duration(secs) * time.Second
+	} else {
+		var s string
+		if err := node.Decode(&s); err != nil {
+			return err
+		}
+		var err error
+		if v, err = time.ParseDuration(s); err != nil {
+			return fmt.Errorf("invalid duration %q, want e.g. 10m", s)
+		}
+	}
+	if v < 0 {
+		return fmt.Errorf("duration %s must not be negative", v)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// StringList is a config value that may be a single string or a list.
+type StringList []string
+
+func (l *StringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var s string
+		if err := node.Decode(&s); err != nil {
+			return err
+		}
+		*l = StringList{s}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// decodeShorthand decodes config into out after expanding a scalar or list
+// shorthand into a map with key, so "min_memory: 8" reads as {gb: 8}.
+func decodeShorthand(config interface{}, key string, out interface{}) error {
+	switch config.(type) {
+	case nil, map[string]interface{}:
+	default:
+		config = map[string]interface{}{key: config}
+	}
+	return Decode(config, out)
+}
@@ -0,0 +1,135 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type siteTagPolicy struct {
+	Tag string
+}
+
+func (p *siteTagPolicy) Check(ctx context.Context) error {
+	if p.Tag != "lab" {
+		return errors.New("policy site_tag: not a lab machine")
+	}
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	t.Cleanup(func() { delete(Registry, "site_tag") })
+	Register("site_tag", func(config interface{}) (Policy, error) {
+		var cfg struct {
+			Tag string `yaml:"tag"`
+		}
+		if err := Decode(config, &cfg); err != nil {
+			return nil, err
+		}
+		return &siteTagPolicy{Tag: cfg.Tag}, nil
+	})
+
+	p, err := NewPolicy("site_tag", map[string]interface{}{"tag": "lab"})
+	if err != nil {
+		t.Fatalf("NewPolicy() error = %v", err)
+	}
+	if err := p.Check(context.Background()); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	// Registered policies nest inside combinators like the built-in ones.
+	p, err = NewPolicy("not", map[string]interface{}{"site_tag": map[string]interface{}{"tag": "office"}})
+	if err != nil {
+		t.Fatalf("NewPolicy(not) error = %v", err)
+	}
+	if err := p.Check(context.Background()); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Register() of a duplicate name did not panic")
+		}
+	}()
+	Register("site_tag", nil)
+}
+
+func TestDecode(t *testing.T) {
+	type config struct {
+		Name    string     `yaml:"name"`
+		Timeout Duration   `yaml:"timeout"`
+		Tags    StringList `yaml:"tags"`
+	}
+	tests := []struct {
+		name    string
+		config  interface{}
+		want    config
+		wantErr string
+	}{
+		{"nil", nil, config{}, ""},
+		{"fields", map[string]interface{}{"name": "a", "timeout": "90s", "tags": []interface{}{"x", "y"}},
+			config{Name: "a", Timeout: Duration(90 * time.Second), Tags: StringList{"x", "y"}}, ""},
+		{"seconds and scalar list", map[string]interface{}{"timeout": 30, "tags": "x"},
+			config{Timeout: Duration(30 * time.Second), Tags: StringList{"x"}}, ""},
+		{"unknown field", map[string]interface{}{"nmae": "a"}, config{}, "field nmae not found"},
+		{"bad duration", map[string]interface{}{"timeout": "soon"}, config{}, `invalid duration "soon"`},
+		{"negative duration", map[string]interface{}{"timeout": "-1m"}, config{}, "must not be negative"},
+		{"wrong type", map[string]interface{}{"name": []interface{}{"a"}}, config{}, "cannot unmarshal !!seq into string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got config
+			err := Decode(tt.config, &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Decode() error = %v, want %q", err, tt.wantErr)
+				}
+				if err != nil && strings.Contains(err.Error(), "line ") {
+					t.Errorf("Decode() error = %v, should not refer to lines of the re-encoded config", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if got.Name != tt.want.Name || got.Timeout != tt.want.Timeout || strings.Join(got.Tags, ",") != strings.Join(tt.want.Tags, ",") {
+				t.Errorf("Decode() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPolicy_UnknownField(t *testing.T) {
+	for name, config := range map[string]interface{}{
+		"device_model":   map[string]interface{}{"allowd": []interface{}{"Latitude 7440"}},
+		"min_free_space": map[string]interface{}{"gb": 40, "wiat": "10m"},
+		"network":        map[string]interface{}{"urls": []interface{}{"https://example.com"}, "statuses": 200},
+		"clock_skew":     map[string]interface{}{"maximum": "1m"},
+	} {
+		if _, err := NewPolicy(name, config); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("NewPolicy(%s, %v) error = %v, want an unknown field error", name, config, err)
+		}
+	}
+}
+
+func TestCheck_Cancelled(t *testing.T) {
+	orig := getPowerStatus
+	t.Cleanup(func() { getPowerStatus = orig })
+	getPowerStatus = func() (powerStatus, error) {
+		return powerStatus{HasBattery: true, BatteryPercent: 50}, nil
+	}
+
+	p := &ACPowerPolicy{Waiter: Waiter{Wait: time.Hour, Poll: time.Minute}}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	err := p.Check(ctx)
+	if err == nil || !strings.Contains(err.Error(), "stopped waiting: context canceled") {
+		t.Errorf("Check() error = %v, want a cancelled wait", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Check() returned after %s, want prompt return on cancel", elapsed)
+	}
+}
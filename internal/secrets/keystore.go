@@ -0,0 +1,143 @@
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/mjoliver/glazier-go/internal/securefile"
+)
+
+// KeySize is the length of a keystore key (AES-256).
+const KeySize = 32
+
+// Keystore is an encrypted local secret store: a YAML map of names to
+// base64 AES-256-GCM ciphertexts. The name is authenticated with each value,
+// so entries cannot be swapped. The key is kept separately, e.g. on the boot
+// media or in the WinPE image.
+type Keystore struct {
+	Path    string
+	key     []byte
+	entries map[string]string
+}
+
+// GenerateKey returns a new random key, base64-encoded.
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// LoadKey reads a base64 key file.
+func LoadKey(path string) ([]byte, error) {
+	if err := securefile.Check(path); err != nil {
+		return nil, fmt.Errorf("keystore key: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("keystore key: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != KeySize {
+		return nil, fmt.Errorf("keystore key %s must be %d base64-encoded bytes", path, KeySize)
+	}
+	return key, nil
+}
+
+// OpenKeystore reads the keystore at path. A missing file is an empty store,
+// so that "glazier secrets set" can create one.
+func OpenKeystore(path string, key []byte) (*Keystore, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("keystore key must be %d bytes", KeySize)
+	}
+	ks := &Keystore{Path: path, key: key, entries: map[string]string{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ks, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keystore: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &ks.entries); err != nil {
+		return nil, fmt.Errorf("keystore %s: %w", path, err)
+	}
+	if ks.entries == nil {
+		ks.entries = map[string]string{}
+	}
+	return ks, nil
+}
+
+func (ks *Keystore) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(ks.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Get decrypts the named secret.
+func (ks *Keystore) Get(ctx context.Context, name string) (string, error) {
+	enc, ok := ks.entries[name]
+	if !ok {
+		return "", fmt.Errorf("%s not found in keystore %s", name, ks.Path)
+	}
+	data, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		return "", fmt.Errorf("%s: corrupt keystore entry", name)
+	}
+	aead, err := ks.aead()
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize() {
+		return "", fmt.Errorf("%s: corrupt keystore entry", name)
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("%s: cannot decrypt (wrong key?)", name)
+	}
+	return string(plain), nil
+}
+
+// Set encrypts value under name. Call Save to write the store.
+func (ks *Keystore) Set(name, value string) error {
+	aead, err := ks.aead()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	ks.entries[name] = base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(value), []byte(name)))
+	return nil
+}
+
+// Names returns the stored secret names, sorted.
+func (ks *Keystore) Names() []string {
+	names := make([]string, 0, len(ks.entries))
+	for n := range ks.entries {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save writes the keystore back to its path.
+func (ks *Keystore) Save() error {
+	data, err := yaml.Marshal(ks.entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(ks.Path, data, 0o600)
+}
@@ -0,0 +1,69 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKeystore(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "keystore.key")
+	k, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(keyPath, []byte(k+"\n"), 0o600)
+	key, err := LoadKey(keyPath)
+	if err != nil {
+		t.Fatalf("LoadKey() error = %v", err)
+	}
+
+	path := filepath.Join(dir, "keystore.yaml")
+	ks, err := OpenKeystore(path, key)
+	if err != nil {
+		t.Fatalf("OpenKeystore(missing) error = %v", err)
+	}
+	ks.Set("join_password", "correct horse")
+	ks.Set("api_token", "tok-123")
+	if err := ks.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "correct horse") {
+		t.Fatal("keystore file contains plaintext")
+	}
+
+	ks, err = OpenKeystore(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ks.Names(); len(got) != 2 || got[0] != "api_token" {
+		t.Errorf("Names() = %v", got)
+	}
+	if v, err := ks.Get(context.Background(), "join_password"); err != nil || v != "correct horse" {
+		t.Errorf("Get() = %q, %v", v, err)
+	}
+	if _, err := ks.Get(context.Background(), "missing"); err == nil {
+		t.Error("Get(missing) expected error")
+	}
+
+	// Swapping entries must fail authentication.
+	ks.entries["api_token"] = ks.entries["join_password"]
+	if _, err := ks.Get(context.Background(), "api_token"); err == nil {
+		t.Error("Get() accepted an entry stored under another name")
+	}
+
+	other := make([]byte, KeySize)
+	wrong, _ := OpenKeystore(path, other)
+	if _, err := wrong.Get(context.Background(), "join_password"); err == nil {
+		t.Error("Get() with the wrong key expected error")
+	}
+
+	os.WriteFile(keyPath, []byte("c2hvcnQ=\n"), 0o600)
+	if _, err := LoadKey(keyPath); err == nil {
+		t.Error("LoadKey(short) expected error")
+	}
+}
@@ -0,0 +1,91 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/deck"
+)
+
+// minMaskLen is the shortest value that is masked. Masking shorter strings
+// would mangle unrelated text in every log line.
+const minMaskLen = 4
+
+const mask = "********"
+
+var (
+	maskMu   sync.RWMutex
+	masked   = map[string]bool{}
+	replacer = strings.NewReplacer()
+)
+
+// Mark registers a value to be masked by Redact, along with the forms it
+// takes when a template passes it through toJson, b64enc or %q.
+func Mark(v string) {
+	if len(v) < minMaskLen {
+		return
+	}
+	maskMu.Lock()
+	defer maskMu.Unlock()
+	if masked[v] {
+		return
+	}
+	for _, form := range encodings(v) {
+		masked[form] = true
+	}
+
+	// Replace longer values first so a secret containing another is masked whole.
+	values := make([]string, 0, len(masked))
+	for m := range masked {
+		values = append(values, m)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	pairs := make([]string, 0, 2*len(values))
+	for _, m := range values {
+		pairs = append(pairs, m, mask)
+	}
+	replacer = strings.NewReplacer(pairs...)
+}
+
+// encodings returns v and its JSON-escaped, Go-quoted and base64 forms.
+// The quoted forms are stored without their quotes so they also match
+// inside longer strings. Base64 is stored unpadded, which masks the padded
+// form too, but only catches v encoded on its own.
+func encodings(v string) []string {
+	forms := []string{v}
+	if data, err := json.Marshal(v); err == nil {
+		forms = append(forms, string(data[1:len(data)-1]))
+	}
+	q := strconv.Quote(v)
+	forms = append(forms, q[1:len(q)-1],
+		base64.RawStdEncoding.EncodeToString([]byte(v)),
+		base64.RawURLEncoding.EncodeToString([]byte(v)))
+	return forms
+}
+
+// Redact masks every marked value in s.
+func Redact(s string) string {
+	maskMu.RLock()
+	r := replacer
+	maskMu.RUnlock()
+	return r.Replace(s)
+}
+
+// redactingBackend masks secrets before messages reach the wrapped backend.
+type redactingBackend struct {
+	deck.Backend
+}
+
+// RedactBackend wraps a deck backend so that marked secret values never
+// reach it.
+func RedactBackend(b deck.Backend) deck.Backend {
+	return redactingBackend{b}
+}
+
+func (b redactingBackend) New(lvl deck.Level, msg string) deck.Composer {
+	return b.Backend.New(lvl, Redact(msg))
+}
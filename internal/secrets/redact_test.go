@@ -0,0 +1,55 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/deck"
+	"github.com/google/deck/backends/logger"
+)
+
+func TestRedact(t *testing.T) {
+	Mark("pa55word")
+	Mark("pa55word-long") // contains the first secret
+	Mark("abc")           // too short to mask
+
+	got := Redact("user=admin pass=pa55word token=pa55word-long abc")
+	want := "user=admin pass=******** token=******** abc"
+	if got != want {
+		t.Errorf("Redact() = %q, want %q", got, want)
+	}
+}
+
+func TestRedact_Encodings(t *testing.T) {
+	secret := `p"ss\<wörd>`
+	Mark(secret)
+
+	jsonForm, _ := json.Marshal(secret)
+	for _, form := range []string{
+		string(jsonForm),
+		fmt.Sprintf("%q", secret),
+		base64.StdEncoding.EncodeToString([]byte(secret)),
+		base64.URLEncoding.EncodeToString([]byte(secret)),
+	} {
+		line := `{"password": ` + form + `}`
+		if got := Redact(line); strings.Contains(got, strings.Trim(form, `"=`)) || !strings.Contains(got, mask) {
+			t.Errorf("Redact(%s) = %s, secret not masked", line, got)
+		}
+	}
+}
+
+func TestRedactBackend(t *testing.T) {
+	Mark("backend-secret")
+	var buf bytes.Buffer
+	d := deck.New()
+	d.Add(RedactBackend(logger.Init(&buf, 0)))
+	d.Infof("joining with %s", "backend-secret")
+	d.Close()
+	if strings.Contains(buf.String(), "backend-secret") || !strings.Contains(buf.String(), "joining with ********") {
+		t.Errorf("log output = %q", buf.String())
+	}
+}
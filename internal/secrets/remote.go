@@ -0,0 +1,72 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mjoliver/glazier-go/internal/httpclient"
+)
+
+// maxSecretSize caps the response read from a secret endpoint.
+const maxSecretSize = 64 << 10
+
+// Remote fetches secrets from an HTTP endpoint with GET <URL>/<name>. The
+// response body is the value, or a JSON object whose "value" field is.
+// Requests go through httpclient, so -auth_config credentials apply.
+// Values are cached for the run.
+type Remote struct {
+	URL string
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+func (r *Remote) Get(ctx context.Context, name string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if v, ok := r.cache[name]; ok {
+		return v, nil
+	}
+
+	u := strings.TrimSuffix(r.URL, "/") + "/" + url.PathEscape(name)
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return "", httpclient.RedactError(err)
+	}
+	resp, err := httpclient.Do(ctx, httpclient.New(30*time.Second), req, httpclient.DefaultRetryPolicy)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSecretSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxSecretSize {
+		return "", fmt.Errorf("response exceeds %d bytes", maxSecretSize)
+	}
+
+	v := strings.TrimSpace(string(data))
+	var doc struct {
+		Value *string `json:"value"`
+	}
+	if strings.HasPrefix(v, "{") {
+		if err := json.Unmarshal(data, &doc); err != nil || doc.Value == nil {
+			return "", fmt.Errorf("JSON response has no string \"value\" field")
+		}
+		v = *doc.Value
+	}
+
+	if r.cache == nil {
+		r.cache = map[string]string{}
+	}
+	r.cache[name] = v
+	return v, nil
+}
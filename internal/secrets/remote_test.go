@@ -0,0 +1,43 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRemote(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		switch r.URL.Path {
+		case "/v1/secrets/plain":
+			w.Write([]byte("plain-value\n"))
+		case "/v1/secrets/json":
+			w.Write([]byte(`{"value": "json-value", "version": 3}`))
+		case "/v1/secrets/bad":
+			w.Write([]byte(`{"data": "x"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	r := &Remote{URL: server.URL + "/v1/secrets/"}
+	ctx := context.Background()
+	for name, want := range map[string]string{"plain": "plain-value", "json": "json-value"} {
+		if v, err := r.Get(ctx, name); err != nil || v != want {
+			t.Errorf("Get(%s) = %q, %v, want %q", name, v, err, want)
+		}
+	}
+	r.Get(ctx, "plain")
+	if hits != 2 {
+		t.Errorf("server hit %d times, want 2 (values are cached)", hits)
+	}
+	for _, name := range []string{"bad", "missing"} {
+		if _, err := r.Get(ctx, name); err == nil {
+			t.Errorf("Get(%s) expected error", name)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Package secrets resolves secret references and keeps their values out of
+// logs.
+//
+// A reference is "scheme:name", e.g. "env:JOIN_PASSWORD" or
+// "keystore:join_password". A bare name uses the default scheme. Configs use
+// references in two ways:
+//
+//	password: {{ secret "keystore:join_password" | toJson }}  # while templating
+//	password: "secret:keystore:join_password"                 # when the task runs
+//
+// Every resolved value is remembered, and backends wrapped with
+// RedactBackend mask it in every message.
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mjoliver/glazier-go/internal/httpclient"
+	"github.com/mjoliver/glazier-go/internal/securefile"
+)
+
+// Prefix marks a string in an action's config as a secret reference.
+const Prefix = "secret:"
+
+// Provider looks up secret values by name.
+type Provider interface {
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// ProviderFunc adapts a function to a Provider.
+type ProviderFunc func(ctx context.Context, name string) (string, error)
+
+func (f ProviderFunc) Get(ctx context.Context, name string) (string, error) {
+	return f(ctx, name)
+}
+
+// Static is a Provider backed by a map. It stands in for real stores in
+// tests.
+type Static map[string]string
+
+func (s Static) Get(ctx context.Context, name string) (string, error) {
+	v, ok := s[name]
+	if !ok {
+		return "", fmt.Errorf("secret %s not found", name)
+	}
+	return v, nil
+}
+
+var (
+	mu            sync.RWMutex
+	providers     = map[string]Provider{}
+	defaultScheme = "env"
+)
+
+// Register adds a provider for references of the form "scheme:name".
+func Register(scheme string, p Provider) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, exists := providers[scheme]; exists {
+		panic(fmt.Sprintf("secret provider %s already registered", scheme))
+	}
+	providers[scheme] = p
+}
+
+// SetDefault sets the scheme used for references without one.
+func SetDefault(scheme string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := providers[scheme]; !ok {
+		return fmt.Errorf("unknown secret provider %q", scheme)
+	}
+	defaultScheme = scheme
+	return nil
+}
+
+func init() {
+	Register("env", ProviderFunc(func(ctx context.Context, name string) (string, error) {
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return v, nil
+	}))
+	Register("file", ProviderFunc(func(ctx context.Context, path string) (string, error) {
+		return securefile.Read(path)
+	}))
+
+	// Let -auth_config rules use "secret:..." credentials.
+	httpclient.RegisterSource("secret", func(ref string) (string, error) {
+		return Resolve(context.Background(), ref)
+	})
+}
+
+// lookup returns the provider and name for a reference.
+func lookup(ref string) (Provider, string, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	scheme, name, ok := strings.Cut(ref, ":")
+	if !ok {
+		scheme, name = defaultScheme, ref
+	}
+	if name == "" {
+		return nil, "", fmt.Errorf("secret reference %q has no name", ref)
+	}
+	p, ok := providers[scheme]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown secret provider %q", scheme)
+	}
+	return p, name, nil
+}
+
+// Resolve looks up a reference and marks its value for redaction.
+func Resolve(ctx context.Context, ref string) (string, error) {
+	p, name, err := lookup(ref)
+	if err != nil {
+		return "", err
+	}
+	v, err := p.Get(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("secret %s: %w", ref, err)
+	}
+	Mark(v)
+	return v, nil
+}
+
+// TemplateFunc returns the secret template function.
+func TemplateFunc(ctx context.Context) func(ref string) (string, error) {
+	return func(ref string) (string, error) {
+		return Resolve(ctx, ref)
+	}
+}
+
+// ResolveRefs returns a copy of an action's config with every "secret:..."
+// string replaced by its value.
+func ResolveRefs(ctx context.Context, v interface{}) (interface{}, error) {
+	return walk(v, func(ref string) (string, error) {
+		return Resolve(ctx, ref)
+	})
+}
+
+// CheckRefs reports malformed references or unknown providers in an action's
+// config without looking up any values.
+func CheckRefs(v interface{}) error {
+	_, err := walk(v, func(ref string) (string, error) {
+		_, _, err := lookup(ref)
+		return ref, err
+	})
+	return err
+}
+
+func walk(v interface{}, fn func(ref string) (string, error)) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		ref, ok := strings.CutPrefix(val, Prefix)
+		if !ok {
+			return val, nil
+		}
+		return fn(ref)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			r, err := walk(item, fn)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			r, err := walk(item, fn)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,97 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mjoliver/glazier-go/internal/httpclient"
+)
+
+func init() {
+	Register("test", Static{"join": "s3cret-join", "api": "s3cret-api"})
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("GLAZIER_TEST_SECRET", "from-env")
+	ctx := context.Background()
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{"test:join", "s3cret-join", false},
+		{"env:GLAZIER_TEST_SECRET", "from-env", false},
+		{"GLAZIER_TEST_SECRET", "from-env", false}, // default scheme
+		{"test:missing", "", true},
+		{"vault:join", "", true},
+		{"test:", "", true},
+	}
+	for _, tt := range tests {
+		got, err := Resolve(ctx, tt.ref)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Resolve(%q) = %q, %v, want %q (error %v)", tt.ref, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	defer SetDefault("env")
+	if err := SetDefault("vault"); err == nil {
+		t.Error("SetDefault(vault) expected error")
+	}
+	if err := SetDefault("test"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := Resolve(context.Background(), "api"); err != nil || v != "s3cret-api" {
+		t.Errorf("Resolve(api) = %q, %v", v, err)
+	}
+}
+
+func TestResolveRefs(t *testing.T) {
+	in := map[string]interface{}{
+		"user":     "join_user",
+		"password": "secret:test:join",
+		"headers":  []interface{}{"secret:test:api", 3},
+	}
+	got, err := ResolveRefs(context.Background(), in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"user":     "join_user",
+		"password": "s3cret-join",
+		"headers":  []interface{}{"s3cret-api", 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveRefs() = %v, want %v", got, want)
+	}
+	if in["password"] != "secret:test:join" {
+		t.Error("ResolveRefs() modified its input")
+	}
+
+	if err := CheckRefs(in); err != nil {
+		t.Errorf("CheckRefs() = %v", err)
+	}
+	if err := CheckRefs(map[string]interface{}{"a": "secret:vault:x"}); err == nil {
+		t.Error("CheckRefs() expected error for an unknown provider")
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "password")
+	os.WriteFile(p, []byte("from-file\n"), 0o600)
+	if v, err := Resolve(context.Background(), "file:"+p); err != nil || v != "from-file" {
+		t.Errorf("Resolve(file) = %q, %v", v, err)
+	}
+}
+
+func TestAuthSource(t *testing.T) {
+	r := httpclient.Rule{Host: "artifacts.example.com", Bearer: "secret:test:api"}
+	if err := r.Resolve(); err != nil {
+		t.Errorf("Rule.Resolve() with a secret reference = %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+// Package securefile reads and writes files holding secrets, such as
+// credential files and keystore keys, which must be readable only by their
+// owner and administrators.
+package securefile
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Check returns an error if path is readable by users other than its owner
+// and administrators.
+func Check(path string) error {
+	return checkProtected(path)
+}
+
+// Read reads a secret from a protected file. Surrounding whitespace is
+// trimmed.
+func Read(path string) (string, error) {
+	if err := checkProtected(path); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// WriteFile writes data to path, first restricting the file to its owner so
+// that Check accepts it. An existing file is truncated and restricted before
+// anything is written.
+func WriteFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := restrict(path); err != nil {
+		f.Close()
+		return fmt.Errorf("restricting %s: %w", path, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func errUnprotected(path, who string) error {
+	return fmt.Errorf("%s is readable by %s; restrict it to the owner", path, who)
+}
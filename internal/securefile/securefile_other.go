@@ -0,0 +1,23 @@
+//go:build !windows
+
+package securefile
+
+import "os"
+
+// checkProtected rejects files readable by group or other.
+func checkProtected(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return errUnprotected(path, "other users (mode "+info.Mode().Perm().String()+")")
+	}
+	return nil
+}
+
+// restrict removes group and other permissions, which os.OpenFile does not
+// do for an existing file.
+func restrict(path string) error {
+	return os.Chmod(path, 0o600)
+}
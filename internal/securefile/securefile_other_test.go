@@ -0,0 +1,35 @@
+//go:build !windows
+
+package securefile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "password")
+	os.WriteFile(p, []byte("x"), 0o600)
+	if err := Check(p); err != nil {
+		t.Errorf("Check(0600) = %v", err)
+	}
+	os.Chmod(p, 0o644)
+	if err := Check(p); err == nil {
+		t.Error("Check(0644) expected error")
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "keystore.key")
+	os.WriteFile(p, []byte("old contents"), 0o644)
+	if err := WriteFile(p, []byte("new-key\n")); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := Check(p); err != nil {
+		t.Errorf("Check() after WriteFile() = %v", err)
+	}
+	if got, err := Read(p); err != nil || got != "new-key" {
+		t.Errorf("Read() = %q, %v", got, err)
+	}
+}
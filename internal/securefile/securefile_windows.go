@@ -0,0 +1,70 @@
+//go:build windows
+
+package securefile
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// broadGroups are well-known groups that must not be granted read access.
+var broadGroups = map[windows.WELL_KNOWN_SID_TYPE]string{
+	windows.WinWorldSid:             "Everyone",
+	windows.WinAuthenticatedUserSid: "Authenticated Users",
+	windows.WinBuiltinUsersSid:      "Users",
+}
+
+const readAccess = windows.GENERIC_READ | windows.GENERIC_ALL | windows.FILE_READ_DATA
+
+// checkProtected rejects files whose DACL lets broad groups read them.
+func checkProtected(path string) error {
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return err
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return err
+	}
+	if dacl == nil {
+		return errUnprotected(path, "everyone (no DACL)")
+	}
+	for i := uint32(0); i < uint32(dacl.AceCount); i++ {
+		var ace *windows.ACCESS_ALLOWED_ACE
+		if err := windows.GetAce(dacl, i, &ace); err != nil {
+			return err
+		}
+		if ace.Header.AceType != windows.ACCESS_ALLOWED_ACE_TYPE || ace.Mask&readAccess == 0 {
+			continue
+		}
+		sid := (*windows.SID)(unsafe.Pointer(&ace.SidStart))
+		for t, name := range broadGroups {
+			if sid.IsWellKnown(t) {
+				return errUnprotected(path, name)
+			}
+		}
+	}
+	return nil
+}
+
+// restrict replaces the file's DACL with one granting full control to the
+// current user, SYSTEM and Administrators only. Inherited entries are
+// dropped, since a 0600 mode means nothing on Windows and the parent folder
+// often grants Users read access.
+func restrict(path string) error {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return err
+	}
+	sd, err := windows.SecurityDescriptorFromString("D:P(A;;FA;;;" + user.User.Sid.String() + ")(A;;FA;;;SY)(A;;FA;;;BA)")
+	if err != nil {
+		return err
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return err
+	}
+	return windows.SetNamedSecurityInfo(path, windows.SE_FILE_OBJECT,
+		windows.DACL_SECURITY_INFORMATION|windows.PROTECTED_DACL_SECURITY_INFORMATION, nil, nil, dacl, nil)
+}
@@ -3,6 +3,7 @@ package template
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -13,6 +14,12 @@ type BuildInfo struct {
 	Timestamp string
 	ImageID   string
 	Username  string
+
+	// Vars holds user-defined variables, available as {{.Vars.name}}.
+	Vars map[string]interface{}
+
+	// Facts holds hardware and system facts, available as {{.Facts.serial}}.
+	Facts map[string]interface{}
 }
 
 // NewBuildInfo collects system information for template context.
@@ -56,6 +63,16 @@ func (b *BuildInfo) Get(key string) (string, error) {
 	case "Username":
 		return b.Username, nil
 	default:
+		if name, ok := strings.CutPrefix(key, "Vars."); ok {
+			if v, ok := b.Vars[name]; ok {
+				return fmt.Sprint(v), nil
+			}
+		}
+		if name, ok := strings.CutPrefix(key, "Facts."); ok {
+			if v, ok := b.Facts[name]; ok {
+				return fmt.Sprint(v), nil
+			}
+		}
 		return "", fmt.Errorf("unknown template variable: %s", key)
 	}
 }
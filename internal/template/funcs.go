@@ -0,0 +1,146 @@
+package template
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FuncMap returns the functions available to config templates. Argument
+// order puts the piped value last, so {{ .Hostname | replace "-" "_" }}
+// works as expected.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		// Strings
+		"lower":      strings.ToLower,
+		"upper":      strings.ToUpper,
+		"trim":       strings.TrimSpace,
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+		"regexMatch": regexp.MatchString,
+
+		// Values
+		"default": dflt,
+		"env":     os.Getenv,
+		"atoi":    atoi,
+
+		// Encoding
+		"toYaml":    toYaml,
+		"toJson":    toJSON,
+		"b64enc":    func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec":    b64dec,
+		"sha256sum": func(s string) string { h := sha256.Sum256([]byte(s)); return hex.EncodeToString(h[:]) },
+
+		// Dates
+		"now":  time.Now,
+		"date": date,
+	}
+}
+
+// atoi converts a string such as a runtime variable to an integer.
+func atoi(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("atoi: %q is not an integer", s)
+	}
+	return n, nil
+}
+
+// join concatenates a []string or []interface{} with sep.
+func join(sep string, list interface{}) (string, error) {
+	switch v := list.(type) {
+	case []string:
+		return strings.Join(v, sep), nil
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, sep), nil
+	default:
+		return "", fmt.Errorf("join: cannot join %T", list)
+	}
+}
+
+// dflt returns value unless it is empty (nil, zero or zero-length), in which
+// case it returns def.
+func dflt(def interface{}, value ...interface{}) interface{} {
+	if len(value) == 0 || isEmpty(value[0]) {
+		return def
+	}
+	return value[0]
+}
+
+func isEmpty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return rv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	default:
+		return rv.IsZero()
+	}
+}
+
+// toYaml renders v as an inline YAML value, quoting strings where needed.
+func toYaml(v interface{}) (string, error) {
+	var buf strings.Builder
+	enc := yaml.NewEncoder(&buf)
+	if err := enc.Encode(v); err != nil {
+		return "", fmt.Errorf("toYaml: %w", err)
+	}
+	enc.Close()
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// toJSON renders v as JSON. JSON strings are valid YAML scalars, which makes
+// this a safe way to quote arbitrary text.
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("toJson: %w", err)
+	}
+	return string(data), nil
+}
+
+func b64dec(s string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("b64dec: %w", err)
+	}
+	return string(data), nil
+}
+
+// date formats t with a Go layout. t may be a time.Time or a string in
+// RFC 3339 or the BuildInfo Timestamp format.
+func date(layout string, t interface{}) (string, error) {
+	switch v := t.(type) {
+	case time.Time:
+		return v.Format(layout), nil
+	case string:
+		for _, in := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+			if parsed, err := time.Parse(in, v); err == nil {
+				return parsed.Format(layout), nil
+			}
+		}
+		return "", fmt.Errorf("date: cannot parse %q", v)
+	default:
+		return "", fmt.Errorf("date: unsupported type %T", t)
+	}
+}
@@ -0,0 +1,55 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFuncMap(t *testing.T) {
+	t.Setenv("GLAZIER_TEST_SITE", "nyc")
+	info := &BuildInfo{Hostname: "WS-Build-01", Stage: "", Timestamp: "2026-03-04T05:06:07"}
+
+	// One row per function, mirroring the reference table in docs/templates.md.
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"lower", `{{ .Hostname | lower }}`, "ws-build-01", false},
+		{"upper", `{{ upper "abc" }}`, "ABC", false},
+		{"trim", `[{{ trim "  x  " }}]`, "[x]", false},
+		{"replace", `{{ .Hostname | replace "-" "_" }}`, "WS_Build_01", false},
+		{"split", `{{ index (split "-" .Hostname) 1 }}`, "Build", false},
+		{"join", `{{ split "-" .Hostname | join "." }}`, "WS.Build.01", false},
+		{"regexMatch", `{{ if regexMatch "^WS-" .Hostname }}workstation{{ end }}`, "workstation", false},
+		{"regexMatch invalid", `{{ regexMatch "(" .Hostname }}`, "", true},
+		{"default empty", `{{ .Stage | default "0" }}`, "0", false},
+		{"default set", `{{ .Hostname | default "x" }}`, "WS-Build-01", false},
+		{"env", `{{ env "GLAZIER_TEST_SITE" }}`, "nyc", false},
+		{"toYaml quotes", `v: {{ "yes: no" | toYaml }}`, `v: 'yes: no'`, false},
+		{"toYaml plain", `v: {{ .Hostname | toYaml }}`, `v: WS-Build-01`, false},
+		{"toJson", `v: {{ "a \"b\"" | toJson }}`, `v: "a \"b\""`, false},
+		{"atoi", `{{ atoi " 42 " | printf "%03d" }}`, "042", false},
+		{"atoi invalid", `{{ atoi "x" }}`, "", true},
+		{"b64enc", `{{ b64enc "hello" }}`, "aGVsbG8=", false},
+		{"b64dec", `{{ b64dec "aGVsbG8=" }}`, "hello", false},
+		{"b64dec invalid", `{{ b64dec "!!" }}`, "", true},
+		{"sha256sum", `{{ sha256sum "hello" }}`, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", false},
+		{"date from timestamp", `{{ date "20060102" .Timestamp }}`, "20260304", false},
+		{"date from now", `{{ if eq (len (date "2006" now)) 4 }}ok{{ end }}`, "ok", false},
+		{"date invalid", `{{ date "2006" "yesterday" }}`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Process([]byte(tt.input), info)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Process() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := strings.TrimSpace(string(result)); !tt.wantErr && got != tt.expected {
+				t.Errorf("Process() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Late-bound expressions use these delimiters. They are left untouched when
+// a config is loaded and rendered by RenderLate just before the task runs.
+const (
+	LateOpen  = "${{"
+	LateClose = "}}"
+)
+
+var lateExpr = regexp.MustCompile(`\$\{\{[^\n]*?\}\}`)
+
+// protectLate rewrites each late-bound expression as a string literal action,
+// so that load-time rendering outputs it unchanged.
+func protectLate(data []byte) []byte {
+	if !bytes.Contains(data, []byte(LateOpen)) {
+		return data
+	}
+	return lateExpr.ReplaceAllFunc(data, func(m []byte) []byte {
+		return []byte("{{" + strconv.Quote(string(m)) + "}}")
+	})
+}
+
+// lateData is the dot for late-bound templates.
+type lateData struct {
+	Runtime map[string]interface{}
+}
+
+// RenderLate renders the late-bound expressions in an action's config
+// against the current runtime variables, available as {{.Runtime.name}}.
+// Referencing an unset runtime variable is an error. A string that is a
+// single expression takes the value of its result unchanged, so
+// "${{ .Runtime.n }}" can fill an integer field when n is a number, and a
+// string such as "007" stays a string.
+func RenderLate(v interface{}, runtime map[string]interface{}) (interface{}, error) {
+	data := lateData{Runtime: runtime}
+	return walkLate(v, func(s string) (interface{}, error) {
+		if lateExpr.FindString(s) == s {
+			if val, ok := lateValue(s, data); ok {
+				return val, nil
+			}
+		}
+		tmpl, err := parseLate(s)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, lateError(err)
+		}
+		return buf.String(), nil
+	})
+}
+
+// lateValue evaluates a single-expression template and returns the value
+// of its pipeline rather than its text, by piping the result into a
+// capturing function. ok is false if the expression cannot be evaluated
+// that way; the caller then renders it as text, which also reports errors.
+func lateValue(s string, data lateData) (val interface{}, ok bool) {
+	inner := strings.TrimSpace(s[len(LateOpen) : len(s)-len(LateClose)])
+	inner = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(inner, "-"), "-"))
+	funcs := FuncMap()
+	funcs["lateCapture"] = func(v interface{}) string {
+		val, ok = v, true
+		return ""
+	}
+	tmpl, err := template.New("task").Delims(LateOpen, LateClose).Funcs(funcs).Option("missingkey=error").
+		Parse(LateOpen + " " + inner + " | lateCapture " + LateClose)
+	if err != nil {
+		return nil, false
+	}
+	if err := tmpl.Execute(io.Discard, data); err != nil {
+		return nil, false
+	}
+	return val, ok
+}
+
+// CheckLate reports syntax errors in the late-bound expressions of an
+// action's config without rendering them.
+func CheckLate(v interface{}) error {
+	_, err := walkLate(v, func(s string) (interface{}, error) {
+		_, err := parseLate(s)
+		return s, err
+	})
+	return err
+}
+
+// HasLate reports whether an action's config contains late-bound expressions.
+func HasLate(v interface{}) bool {
+	found := false
+	walkLate(v, func(s string) (interface{}, error) {
+		found = true
+		return s, nil
+	})
+	return found
+}
+
+func parseLate(s string) (*template.Template, error) {
+	tmpl, err := template.New("task").Delims(LateOpen, LateClose).Funcs(FuncMap()).Option("missingkey=error").Parse(s)
+	if err != nil {
+		return nil, lateError(err)
+	}
+	return tmpl, nil
+}
+
+var (
+	lateLocation = regexp.MustCompile(`^template: task:\d+(?::\d+)?: (.*)$`)
+	lateExec     = regexp.MustCompile(`^executing "task" at <([^>]*)>: (.*)$`)
+)
+
+// lateError strips text/template's location prefix, which refers to the
+// single value rather than the config file.
+func lateError(err error) error {
+	msg := err.Error()
+	if m := lateLocation.FindStringSubmatch(msg); m != nil {
+		msg = m[1]
+	}
+	if m := lateExec.FindStringSubmatch(msg); m != nil {
+		msg = m[2]
+		if errMapKey.MatchString(m[2]) || errField.MatchString(m[2]) {
+			msg = fmt.Sprintf("undefined variable %s (not set by an earlier task)", m[1])
+		}
+	}
+	return fmt.Errorf("late-bound template: %s", msg)
+}
+
+func walkLate(v interface{}, fn func(string) (interface{}, error)) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		if !strings.Contains(val, LateOpen) {
+			return val, nil
+		}
+		return fn(val)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			r, err := walkLate(item, fn)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			r, err := walkLate(item, fn)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,78 @@
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProcess_KeepsLateExpressions(t *testing.T) {
+	input := "host: {{.Hostname}}\nstage: \"${{ .Runtime.stage }}\"\nboth: \"{{.Hostname}}-${{ .Runtime.path | upper }}\""
+	got, err := Process([]byte(input), &BuildInfo{Hostname: "ws1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "host: ws1\nstage: \"${{ .Runtime.stage }}\"\nboth: \"ws1-${{ .Runtime.path | upper }}\""
+	if string(got) != want {
+		t.Errorf("Process() = %q, want %q", got, want)
+	}
+
+	vars, _ := Variables([]byte(input), Options{})
+	if strings.Join(vars, ",") != ".Hostname" {
+		t.Errorf("Variables() = %v, late expressions should not be listed", vars)
+	}
+}
+
+func TestRenderLate(t *testing.T) {
+	runtime := map[string]interface{}{"stage": "50", "dir": `C:\Apps`, "count": 3, "serial": "007", "id": "0x1F", "flag": "true"}
+	in := map[string]interface{}{
+		"plain":   "no templates {{here}}",
+		"stage":   "${{ .Runtime.stage }}",
+		"path":    `${{ .Runtime.dir }}\agent.msi`,
+		"args":    []interface{}{"/stage=${{ .Runtime.stage }}", 7},
+		"retries": 2,
+		"count":   "${{ .Runtime.count }}",
+		"delay":   "${{- .Runtime.stage | atoi -}}",
+		"serial":  "${{ .Runtime.serial }}",
+		"id":      "${{ .Runtime.id }}",
+		"flag":    "${{ .Runtime.flag }}",
+		"upper":   "${{ .Runtime.id | upper }}",
+	}
+	got, err := RenderLate(in, runtime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"plain":   "no templates {{here}}",
+		"stage":   "50", // runtime strings are never re-parsed
+		"path":    `C:\Apps\agent.msi`,
+		"args":    []interface{}{"/stage=50", 7},
+		"retries": 2,
+		"count":   3, // a whole-value expression keeps its type
+		"delay":   50,
+		"serial":  "007",
+		"id":      "0x1F",
+		"flag":    "true",
+		"upper":   "0X1F",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RenderLate() = %#v, want %#v", got, want)
+	}
+
+	_, err = RenderLate(map[string]interface{}{"id": "${{ .Runtime.missing }}"}, runtime)
+	if err == nil || !strings.Contains(err.Error(), "id: late-bound template: undefined variable .Runtime.missing") {
+		t.Errorf("RenderLate(missing) error = %v", err)
+	}
+}
+
+func TestCheckLate(t *testing.T) {
+	if err := CheckLate(map[string]interface{}{"a": "${{ .Runtime.x | lower }}"}); err != nil {
+		t.Errorf("CheckLate() = %v", err)
+	}
+	if err := CheckLate([]interface{}{"${{ .Runtime.x | nosuchfunc }}"}); err == nil {
+		t.Error("CheckLate() expected error for an unknown function")
+	}
+	if !HasLate(map[string]interface{}{"a": []interface{}{1, "${{ .Runtime.x }}"}}) || HasLate("{{ .Hostname }}") {
+		t.Error("HasLate() mismatch")
+	}
+}
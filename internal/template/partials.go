@@ -0,0 +1,94 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Partial is a file of named templates ({{define "name"}}...{{end}}) that a
+// config can render with {{template "name" .}}. Text outside the defines is
+// ignored.
+type Partial struct {
+	Name string // path or URL, used in errors
+	Data []byte
+}
+
+// partialName is the internal template name of the i'th partial file.
+func partialName(i int) string {
+	return "partial" + strconv.Itoa(i)
+}
+
+// standaloneTemplate matches a {{template}} action alone on its line.
+var standaloneTemplate = regexp.MustCompile("(?m)^([ \\t]*)\\{\\{-?\\s*template\\s+(\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`)\\s*(.*?)\\s*-?\\}\\}[ \\t]*$")
+
+// reindentTemplates rewrites each {{template}} action that stands alone on
+// its line as a call to partial, so the output is indented to match.
+func reindentTemplates(data []byte) []byte {
+	if !bytes.Contains(data, []byte("template")) {
+		return data
+	}
+	return standaloneTemplate.ReplaceAllFunc(data, func(m []byte) []byte {
+		g := standaloneTemplate.FindSubmatch(m)
+		indent, name, pipe := string(g[1]), string(g[2]), string(g[3])
+		if pipe == "" {
+			pipe = "nil"
+		} else {
+			pipe = "(" + pipe + ")"
+		}
+		return []byte(fmt.Sprintf("%s{{partial %s %s %s}}", indent, name, pipe, strconv.Quote(indent)))
+	})
+}
+
+// partialFunc returns the partial function: it executes a named template
+// and indents every line after the first to match the call site.
+func partialFunc(root *template.Template, opts Options) func(string, interface{}, string) (string, error) {
+	return func(name string, data interface{}, indent string) (string, error) {
+		if root.Lookup(name) == nil {
+			return "", fmt.Errorf("no template %q is defined; list the file that defines it under templates:", name)
+		}
+		var buf bytes.Buffer
+		if err := root.ExecuteTemplate(&buf, name, data); err != nil {
+			return "", describe(opts, nil, err)
+		}
+		return reindent(buf.String(), indent), nil
+	}
+}
+
+// reindent removes the common leading indentation of s, then prefixes each
+// line but the first with indent. Leading and trailing blank lines, which
+// {{define}} blocks usually carry, are dropped.
+func reindent(s, indent string) string {
+	lines := strings.Split(s, "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	common := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		n := len(l) - len(strings.TrimLeft(l, " \t"))
+		if common < 0 || n < common {
+			common = n
+		}
+	}
+	for i, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			lines[i] = ""
+			continue
+		}
+		l = l[common:]
+		if i > 0 {
+			l = indent + l
+		}
+		lines[i] = l
+	}
+	return strings.Join(lines, "\n")
+}
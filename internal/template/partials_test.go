@@ -0,0 +1,85 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+const hardening = `
+{{define "hardening"}}
+- registry.set:
+    path: SOFTWARE\Policies\Corp
+    name: Site
+    value: {{.Vars.site}}
+{{end}}
+{{define "packages"}}
+- chrome
+- 7zip
+{{end}}
+{{define "broken"}}
+x: {{.Vars.missing}}
+{{end}}
+`
+
+func TestProcessWith_Partials(t *testing.T) {
+	input := `tasks:
+  {{template "hardening" .}}
+  - googet.install:
+      packages:
+        {{- template "packages"}}
+        - extra
+`
+	info := &BuildInfo{Vars: map[string]interface{}{"site": "nyc"}}
+	opts := Options{Name: "build.yaml", Partials: []Partial{{Name: "partials/common.yaml", Data: []byte(hardening)}}}
+	got, err := ProcessWith([]byte(input), info, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `tasks:
+  - registry.set:
+      path: SOFTWARE\Policies\Corp
+      name: Site
+      value: nyc
+  - googet.install:
+      packages:
+        - chrome
+        - 7zip
+        - extra
+`
+	if string(got) != want {
+		t.Errorf("ProcessWith() =\n%s\nwant\n%s", got, want)
+	}
+
+	vars, _ := Variables([]byte(input), opts)
+	if strings.Join(vars, ",") != ".Vars.site" {
+		t.Errorf("Variables() = %v", vars)
+	}
+}
+
+func TestProcessWith_PartialErrors(t *testing.T) {
+	opts := Options{Name: "build.yaml", Strict: true, Partials: []Partial{{Name: "partials/common.yaml", Data: []byte(hardening)}}}
+	info := &BuildInfo{}
+
+	_, err := ProcessWith([]byte("a:\n  {{template \"broken\" .}}\n"), info, opts)
+	if err == nil || err.Error() != "build.yaml:2:4: partials/common.yaml:13:10: undefined variable .Vars.missing" {
+		t.Errorf("error in partial = %v", err)
+	}
+
+	_, err = ProcessWith([]byte(`{{template "nope" .}}`), info, opts)
+	if err == nil || !strings.Contains(err.Error(), `no template "nope" is defined`) {
+		t.Errorf("unknown partial error = %v", err)
+	}
+
+	opts.Partials[0].Data = []byte("{{define \"x\"}}\n{{.Bad\n{{end}}")
+	_, err = ProcessWith([]byte("a: 1"), info, opts)
+	if err == nil || !strings.HasPrefix(err.Error(), "partials/common.yaml:3: ") {
+		t.Errorf("parse error in partial = %v", err)
+	}
+}
+
+func TestReindent(t *testing.T) {
+	got := reindent("\n    a: 1\n    b:\n\n      c: 2\n\n", "  ")
+	if want := "a: 1\n  b:\n\n    c: 2"; got != want {
+		t.Errorf("reindent() = %q, want %q", got, want)
+	}
+}
@@ -3,25 +3,249 @@ package template
 import (
 	"bytes"
 	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"text/template"
+	"text/template/parse"
 )
 
+// Options controls how a config is rendered.
+type Options struct {
+	// Name identifies the config in errors, usually its path or URL.
+	Name string
+
+	// Funcs adds functions, such as lookup, whose behaviour depends on where
+	// the config was loaded from.
+	Funcs map[string]interface{}
+
+	// Strict makes a missing map key, such as {{.Vars.missing}}, an error
+	// instead of rendering "<no value>".
+	Strict bool
+
+	// Partials provide named templates for {{template "name" .}}.
+	Partials []Partial
+}
+
 // Process applies Go text/template to the input data using BuildInfo context.
 func Process(data []byte, info *BuildInfo) ([]byte, error) {
+	return ProcessWith(data, info, Options{})
+}
+
+// ProcessWith is Process with options.
+func ProcessWith(data []byte, info *BuildInfo, opts Options) ([]byte, error) {
 	if info == nil {
 		// No template processing if BuildInfo is nil
 		return data, nil
 	}
 
-	tmpl, err := template.New("config").Parse(string(data))
+	tmpl, err := parseTemplate(data, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse template: %w", err)
+		return nil, describe(opts, info, err)
+	}
+	if opts.Strict {
+		tmpl.Option("missingkey=error")
 	}
 
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, info); err != nil {
-		return nil, fmt.Errorf("failed to execute template: %w", err)
+		return nil, describe(opts, info, err)
 	}
 
 	return buf.Bytes(), nil
 }
+
+func parseTemplate(data []byte, opts Options) (*template.Template, error) {
+	tmpl := template.New("config").Funcs(FuncMap()).Funcs(opts.Funcs)
+	tmpl.Funcs(template.FuncMap{"partial": partialFunc(tmpl, opts)})
+	for i, p := range opts.Partials {
+		if _, err := tmpl.New(partialName(i)).Parse(string(reindentTemplates(protectLate(p.Data)))); err != nil {
+			return nil, err
+		}
+	}
+	return tmpl.Parse(string(reindentTemplates(protectLate(data))))
+}
+
+// Error is a template error located in a config file.
+type Error struct {
+	File     string
+	Line     int
+	Col      int    // 0 if unknown
+	Variable string // e.g. ".Vars.site", for undefined variables
+	Msg      string
+}
+
+func (e *Error) Error() string {
+	loc := fmt.Sprintf("%s:%d", e.File, e.Line)
+	if e.Col > 0 {
+		loc += fmt.Sprintf(":%d", e.Col)
+	}
+	return loc + ": " + e.Msg
+}
+
+var (
+	// template: config:3:10: executing "config" at <.ImageId>: can't evaluate ...
+	errLocation = regexp.MustCompile(`^template: (config|partial\d+):(\d+)(?::(\d+))?: (.*)$`)
+	errExec     = regexp.MustCompile(`^executing "[^"]*" at <([^>]*)>: (.*)$`)
+	errField    = regexp.MustCompile(`^can't evaluate field (\w+)`)
+	errMapKey   = regexp.MustCompile(`^map has no entry for key "([^"]*)"`)
+)
+
+// describe rewrites a text/template error to name the file, line and
+// undefined variable, with a suggestion when only the case is wrong.
+func describe(opts Options, info *BuildInfo, err error) error {
+	name := opts.Name
+	if name == "" {
+		name = "config"
+	}
+	m := errLocation.FindStringSubmatch(err.Error())
+	if m == nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	for i, p := range opts.Partials {
+		if m[1] == partialName(i) {
+			name = p.Name
+		}
+	}
+	e := &Error{File: name, Msg: m[4]}
+	e.Line, _ = strconv.Atoi(m[2])
+	e.Col, _ = strconv.Atoi(m[3])
+
+	x := errExec.FindStringSubmatch(e.Msg)
+	if x == nil {
+		return e
+	}
+	at, msg := x[1], x[2]
+	// Errors inside a reindented partial are already located.
+	e.Msg = strings.TrimPrefix(msg, "error calling partial: ")
+	if errField.MatchString(msg) || errMapKey.MatchString(msg) {
+		e.Variable = at
+		e.Msg = "undefined variable " + at
+		if s := suggest(info, at); s != "" {
+			e.Msg += fmt.Sprintf(" (did you mean %s?)", s)
+		}
+	}
+	return e
+}
+
+// suggest returns a defined variable that differs from ref only in case.
+func suggest(info *BuildInfo, ref string) string {
+	parts := strings.Split(strings.TrimPrefix(ref, "."), ".")
+	v := reflect.ValueOf(info)
+	var out []string
+	for _, p := range parts {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return ""
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			f, ok := v.Type().FieldByNameFunc(func(n string) bool { return strings.EqualFold(n, p) })
+			if !ok {
+				return ""
+			}
+			out, v = append(out, f.Name), v.FieldByIndex(f.Index)
+		case reflect.Map:
+			var found reflect.Value
+			for _, k := range v.MapKeys() {
+				if k.Kind() == reflect.String && strings.EqualFold(k.String(), p) {
+					found = k
+					break
+				}
+			}
+			if !found.IsValid() {
+				return ""
+			}
+			out, v = append(out, found.String()), v.MapIndex(found)
+		default:
+			return ""
+		}
+	}
+	if s := "." + strings.Join(out, "."); s != ref {
+		return s
+	}
+	return ""
+}
+
+// Variables returns the template variables a config uses, such as
+// ".Hostname" or ".Vars.site", sorted. Templates it calls from partials are
+// followed. Fields evaluated inside {{with}} or {{range}} are relative to
+// another value and are not listed.
+func Variables(data []byte, opts Options) ([]string, error) {
+	tmpl, err := parseTemplate(data, opts)
+	if err != nil {
+		return nil, describe(opts, nil, err)
+	}
+	seen := map[string]bool{}
+	visited := map[string]bool{}
+	var walk func(n parse.Node, top bool)
+	follow := func(name string) {
+		if t := tmpl.Lookup(name); t != nil && t.Tree != nil && !visited[name] {
+			visited[name] = true
+			walk(t.Tree.Root, true)
+		}
+	}
+	walk = func(n parse.Node, top bool) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c, top)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, top)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Cmds {
+				walk(c, top)
+			}
+		case *parse.CommandNode:
+			if len(n.Args) > 1 {
+				if id, ok := n.Args[0].(*parse.IdentifierNode); ok && id.Ident == "partial" {
+					if name, ok := n.Args[1].(*parse.StringNode); ok {
+						follow(name.Text)
+					}
+				}
+			}
+			for _, a := range n.Args {
+				walk(a, top)
+			}
+		case *parse.ChainNode:
+			walk(n.Node, top)
+		case *parse.FieldNode:
+			if top {
+				seen["."+strings.Join(n.Ident, ".")] = true
+			}
+		case *parse.IfNode:
+			walk(n.Pipe, top)
+			walk(n.List, top)
+			walk(n.ElseList, top)
+		case *parse.WithNode:
+			walk(n.Pipe, top)
+			walk(n.List, false)
+			walk(n.ElseList, top)
+		case *parse.RangeNode:
+			walk(n.Pipe, top)
+			walk(n.List, false)
+			walk(n.ElseList, top)
+		case *parse.TemplateNode:
+			walk(n.Pipe, top)
+			follow(n.Name)
+		}
+	}
+	follow("config")
+	vars := make([]string, 0, len(seen))
+	for v := range seen {
+		vars = append(vars, v)
+	}
+	sort.Strings(vars)
+	return vars, nil
+}
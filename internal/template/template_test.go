@@ -13,6 +13,7 @@ func TestProcess(t *testing.T) {
 		ImageID:   "win11-v1",
 		Username:  "admin",
 		Timestamp: "2026-01-01T00:00:00",
+		Facts:     map[string]interface{}{"serial": "ABC123", "tpm": true},
 	}
 
 	tests := []struct {
@@ -71,6 +72,11 @@ func TestProcess(t *testing.T) {
 			input:    "user: {{.Username}}",
 			expected: "user: admin",
 		},
+		{
+			name:     "facts",
+			input:    "{{if .Facts.tpm}}tpm-{{.Facts.serial}}{{end}}",
+			expected: "tpm-ABC123",
+		},
 	}
 
 	for _, tt := range tests {
@@ -191,3 +197,60 @@ func TestProcess_EndToEnd(t *testing.T) {
 		t.Error("Process() should preserve googet.install action")
 	}
 }
+
+func TestProcessWith_Errors(t *testing.T) {
+	info := &BuildInfo{
+		Hostname: "test-host",
+		Vars:     map[string]interface{}{"site": "nyc"},
+	}
+	tests := []struct {
+		name    string
+		input   string
+		strict  bool
+		want    string // rendered output, or error text
+		wantErr bool
+	}{
+		{"wrong case field", "a: 1\nb: {{.ImageId}}", false, "build.yaml:2:5: undefined variable .ImageId (did you mean .ImageID?)", true},
+		{"unknown field", "{{.Nope}}", false, "build.yaml:1:2: undefined variable .Nope", true},
+		{"missing var lenient", "{{.Vars.missing}}", false, "<no value>", false},
+		{"missing var strict", "x\n\n{{.Vars.missing}}", true, "build.yaml:3:7: undefined variable .Vars.missing", true},
+		{"wrong case var strict", "{{.Vars.Site}}", true, "build.yaml:1:7: undefined variable .Vars.Site (did you mean .Vars.site?)", true},
+		{"strict defined", "{{.Vars.site}}-{{index .Vars \"other\"}}", true, "nyc-<no value>", false},
+		{"parse error", "ok\n{{.Hostname", false, "build.yaml:2: unclosed action", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessWith([]byte(tt.input), info, Options{Name: "build.yaml", Strict: tt.strict})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProcessWith() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if err.Error() != tt.want {
+					t.Errorf("ProcessWith() error = %q, want %q", err, tt.want)
+				}
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("ProcessWith() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVariables(t *testing.T) {
+	input := `
+{{if .Vars.enabled}}host: {{.Hostname | lower}}{{end}}
+image: {{default "base" .ImageID}}
+{{range .Facts.disks}}- {{.name}}{{end}}
+{{with .Vars.owner}}owner: {{.}}{{end}}
+serial: {{.Facts.serial}}
+`
+	got, err := Variables([]byte(input), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".Facts.disks", ".Facts.serial", ".Hostname", ".ImageID", ".Vars.enabled", ".Vars.owner"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Variables() = %v, want %v", got, want)
+	}
+}